@@ -25,6 +25,8 @@ type Config struct {
 
 	TrustedProxies []string `yaml:"trusted_proxies"` // 受信任反向代理网段
 
+	Secret string `yaml:"secret"` // 服务端签名密钥
+
 	DB             ConfigDatabase `yaml:"db"`       // 程序内部使用的 KV 存储
 	UserDB         ConfigDatabase `yaml:"user_db"`  // 用户脚本使用的 KV 存储
 	LegacyDatabase ConfigDatabase `yaml:"database"` // 兼容旧配置
@@ -95,10 +97,26 @@ func (c *Config) statusTemplate(status int) *template.Template {
 }
 
 type ConfigAuth struct {
+	Provider      string           `yaml:"provider"` // 认证提供方，为空时使用内容 Provider
 	SessionTTL    time.Duration    `yaml:"session_ttl"`
+	SessionMaxTTL time.Duration    `yaml:"session_max_ttl"`
 	StateTTL      time.Duration    `yaml:"state_ttl"`
 	AuthzCacheTTL time.Duration    `yaml:"authz_cache_ttl"`
 	Cookie        ConfigAuthCookie `yaml:"cookie"`
+	SSOHost       string           `yaml:"sso_host"`      // 统一登录域名，需要配置 secret
+	ShareMaxTTL   time.Duration    `yaml:"share_max_ttl"` // 分享链接最长有效期，签发需要配置 secret
+	SessionStore  string           `yaml:"session_store"` // 会话存储方式: kv (默认) 或 cookie
+	SessionKeys   []string         `yaml:"session_keys"`  // cookie 会话加密密钥，首个用于加密；为空时使用 secret
+	providers     map[string]json.RawMessage
+}
+
+// ProviderConfig 返回 auth.<name> 下的认证提供方配置
+func (c ConfigAuth) ProviderConfig(name string) (json.RawMessage, bool) {
+	if c.providers == nil {
+		return nil, false
+	}
+	value, ok := c.providers[name]
+	return value, ok
 }
 
 type ConfigAuthCookie struct {
@@ -115,6 +133,8 @@ type ConfigServer struct {
 
 type ConfigPage struct {
 	DefaultBranch   string `yaml:"default_branch"`
+	RepoSubdomain   bool   `yaml:"repo_subdomain"`  // 启用 <repo>.<owner>.<domain> 子域名模式
+	OrgConfigRepo   string `yaml:"org_config_repo"` // 组织级配置仓库名称
 	ErrUnauthorized string `yaml:"401"`
 	ErrForbidden    string `yaml:"403"`
 	ErrNotFoundPage string `yaml:"404"`
@@ -171,7 +191,44 @@ func LoadConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if c.Auth != nil && c.Auth.Provider != "" && c.Auth.Provider != c.Provider.Type {
+		c.Auth.providers, err = loadProviderConfigs(data, "auth", map[string]struct{}{
+			"provider":        {},
+			"session_ttl":     {},
+			"session_max_ttl": {},
+			"state_ttl":       {},
+			"authz_cache_ttl": {},
+			"cookie":          {},
+			"sso_host":        {},
+			"share_max_ttl":   {},
+			"session_store":   {},
+			"session_keys":    {},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := core.GetAuthProviderFactory(c.Auth.Provider); !ok {
+			return nil, errors.Errorf("unknown auth provider %s", c.Auth.Provider)
+		}
+		if _, ok := c.Auth.ProviderConfig(c.Auth.Provider); !ok {
+			return nil, errors.Errorf("auth.%s config is required", c.Auth.Provider)
+		}
+	}
 
+	if c.Auth != nil && c.Auth.SSOHost != "" && c.Secret == "" {
+		return nil, errors.New("auth.sso_host requires secret")
+	}
+	if c.Auth != nil {
+		switch c.Auth.SessionStore {
+		case "", core.SessionStoreKV:
+		case core.SessionStoreCookie:
+			if len(c.Auth.SessionKeys) == 0 && c.Secret == "" {
+				return nil, errors.New("auth.session_store cookie requires auth.session_keys or secret")
+			}
+		default:
+			return nil, errors.Errorf("unknown auth.session_store %s", c.Auth.SessionStore)
+		}
+	}
 	if c.DB.URL == "" {
 		c.DB = c.LegacyDatabase
 		if c.DB.URL != "" {
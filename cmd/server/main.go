@@ -25,13 +25,15 @@ import (
 )
 
 var (
-	configPath = "config-local.yaml"
-	debug      = false
+	configPath    = "config-local.yaml"
+	debug         = false
+	revokeSubject = ""
 )
 
 func init() {
 	flag.StringVar(&configPath, "conf", configPath, "config file path")
 	flag.BoolVar(&debug, "debug", debug, "debug mode")
+	flag.StringVar(&revokeSubject, "revoke-subject", revokeSubject, "revoke all login sessions of the auth subject and exit")
 }
 
 func main() {
@@ -107,17 +109,34 @@ func main() {
 	defer fileStorage.Close()
 	var authService *core.AuthService
 	if config.Auth != nil {
-		authProvider, ok := provider.(core.ProviderWithAuth)
-		if !ok {
-			log.Fatalf("provider %s does not support auth", config.Provider.Type)
-		}
-		if !authProvider.AuthEnabled() {
-			log.Fatalf("provider %s auth is not fully configured", config.Provider.Type)
+		var authProvider core.AuthProvider
+		if config.Auth.Provider == "" || config.Auth.Provider == config.Provider.Type {
+			contentAuth, ok := provider.(core.ProviderWithAuth)
+			if !ok {
+				log.Fatalf("provider %s does not support auth", config.Provider.Type)
+			}
+			if !contentAuth.AuthEnabled() {
+				log.Fatalf("provider %s auth is not fully configured", config.Provider.Type)
+			}
+			authProvider = contentAuth
+		} else {
+			factory, _ := core.GetAuthProviderFactory(config.Auth.Provider)
+			raw, _ := config.Auth.ProviderConfig(config.Auth.Provider)
+			authProvider, err = factory(http.DefaultClient, raw)
+			if err != nil {
+				log.Fatalf("failed to create auth provider %s: %v", config.Auth.Provider, err)
+			}
 		}
 		authService = core.NewAuthService(authProvider, db.Child("auth"), core.AuthServiceConfig{
 			SessionTTL:     config.Auth.SessionTTL,
+			SessionMaxTTL:  config.Auth.SessionMaxTTL,
 			StateTTL:       config.Auth.StateTTL,
 			AuthzCacheTTL:  config.Auth.AuthzCacheTTL,
+			Secret:         []byte(config.Secret),
+			SSOHost:        strings.ToLower(config.Auth.SSOHost),
+			ShareMaxTTL:    config.Auth.ShareMaxTTL,
+			SessionStore:   config.Auth.SessionStore,
+			SessionKeys:    sessionKeys(config.Auth.SessionKeys),
 			CookieName:     config.Auth.Cookie.Name,
 			CookieSecure:   config.Auth.Cookie.Secure,
 			CookieDomain:   config.Auth.Cookie.Domain,
@@ -133,6 +152,16 @@ func main() {
 			},
 		})
 	}
+	if revokeSubject != "" {
+		if authService == nil {
+			log.Fatalln("revoke-subject requires auth config")
+		}
+		if err = authService.RevokeSubject(context.Background(), revokeSubject); err != nil {
+			log.Fatalln(err)
+		}
+		slog.Info("auth sessions revoked", "subject", revokeSubject)
+		return
+	}
 	if config.Filters == nil {
 		config.Filters = make(map[string]map[string]any)
 	}
@@ -148,6 +177,11 @@ func main() {
 		pkg.WithFilterConfig(config.Filters),
 		pkg.WithTrustedProxies(config.TrustedProxies),
 		pkg.WithAuth(authService),
+		pkg.WithSecret([]byte(config.Secret)),
+		pkg.WithRepoSubdomain(config.Page.RepoSubdomain),
+	}
+	if config.Page.OrgConfigRepo != "" {
+		serverOptions = append(serverOptions, pkg.WithOrgConfigRepo(config.Page.OrgConfigRepo))
 	}
 	if config.Server.StaticCacheMaxAge != nil {
 		if *config.Server.StaticCacheMaxAge <= 0 {
@@ -216,6 +250,14 @@ func parseSameSite(value string) http.SameSite {
 	}
 }
 
+func sessionKeys(values []string) [][]byte {
+	keys := make([][]byte, 0, len(values))
+	for _, value := range values {
+		keys = append(keys, []byte(value))
+	}
+	return keys
+}
+
 func logInject() {
 	level := slog.LevelInfo
 	if debug {
@@ -19,6 +19,11 @@ declare global {
          * Alias of {@link Request.ip} kept for compatibility.
          */
         readonly RemoteIP: string;
+        /**
+         * Parameters captured by the matched route pattern, e.g. `:slug` or `splat`.
+         * Empty for requests created through the `Request` constructor.
+         */
+        readonly params: Readonly<Record<string, string>>;
     }
 
     interface Response {
@@ -208,6 +213,10 @@ declare global {
         org: string;
         repo: string;
         commit: string;
+        /**
+         * Subdomain label matched by a wildcard alias such as `*.docs.example.com`; empty otherwise.
+         */
+        subdomain: string;
     }
 
     /**
@@ -15,6 +15,8 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/afero v1.15.0
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/crypto v0.51.0
+	golang.org/x/net v0.54.0
 	gopkg.d7z.net/middleware v0.0.0-20260515175002-5efba04b1d0f
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -74,8 +76,6 @@ require (
 	go.opentelemetry.io/otel/trace v1.43.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.28.0 // indirect
-	golang.org/x/crypto v0.51.0 // indirect
-	golang.org/x/net v0.54.0 // indirect
 	golang.org/x/sys v0.44.0 // indirect
 	golang.org/x/text v0.37.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20260511170946-3700d4141b60 // indirect
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	PermissionNone  = ""
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+	PermissionAdmin = "admin"
+)
+
+var permissionLevels = map[string]int{
+	PermissionNone:  0,
+	PermissionRead:  1,
+	PermissionWrite: 2,
+	PermissionAdmin: 3,
+}
+
+// AccessProvider 可选接口，解析访问规则中的组织、团队成员关系与仓库权限
+type AccessProvider interface {
+	// RepoPermission 返回会话在仓库上的权限，无权限时返回 PermissionNone
+	RepoPermission(ctx context.Context, sess *AuthSession, owner, repo string) (string, error)
+	IsOrgMember(ctx context.Context, sess *AuthSession, org string) (bool, error)
+	IsTeamMember(ctx context.Context, sess *AuthSession, org, team string) (bool, error)
+}
+
+// PageAccess 页面访问规则，声明后页面需要登录，满足任一条件即可访问
+type PageAccess struct {
+	Users      []string `yaml:"users" json:"users,omitempty"`           // 用户名
+	Orgs       []string `yaml:"orgs" json:"orgs,omitempty"`             // 组织成员
+	Teams      []string `yaml:"teams" json:"teams,omitempty"`           // 团队成员，格式为 org/team
+	Permission string   `yaml:"permission" json:"permission,omitempty"` // 最低仓库权限 read / write / admin
+}
+
+// Normalize 校验并规范化访问规则
+func (a *PageAccess) Normalize() error {
+	if a == nil {
+		return nil
+	}
+	for i, user := range a.Users {
+		if a.Users[i] = strings.TrimSpace(user); a.Users[i] == "" {
+			return errors.New("access user cannot be empty")
+		}
+	}
+	for i, org := range a.Orgs {
+		if a.Orgs[i] = strings.TrimSpace(org); a.Orgs[i] == "" {
+			return errors.New("access org cannot be empty")
+		}
+	}
+	for i, team := range a.Teams {
+		team = strings.TrimSpace(team)
+		org, name, ok := strings.Cut(team, "/")
+		if !ok || org == "" || name == "" || strings.Contains(name, "/") {
+			return errors.Errorf("invalid access team %q, expected org/team", a.Teams[i])
+		}
+		a.Teams[i] = team
+	}
+	a.Permission = strings.ToLower(strings.TrimSpace(a.Permission))
+	if _, ok := permissionLevels[a.Permission]; !ok {
+		return errors.Errorf("invalid access permission %q", a.Permission)
+	}
+	if len(a.Users) == 0 && len(a.Orgs) == 0 && len(a.Teams) == 0 && a.Permission == PermissionNone {
+		return errors.New("access must declare users, orgs, teams or permission")
+	}
+	return nil
+}
+
+// Digest 返回规则内容摘要
+func (a *PageAccess) Digest() string {
+	data, _ := json.Marshal(a)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Allows 判断会话是否满足访问规则，仅按用户名判断时不需要 AccessProvider
+func (a *PageAccess) Allows(ctx context.Context, provider AuthProvider, sess *AuthSession, owner, repo string) (bool, error) {
+	for _, user := range a.Users {
+		if strings.EqualFold(user, sess.Identity.Name) {
+			return true, nil
+		}
+	}
+	if len(a.Orgs) == 0 && len(a.Teams) == 0 && a.Permission == PermissionNone {
+		return false, nil
+	}
+	resolver, ok := provider.(AccessProvider)
+	if !ok {
+		return false, errors.New("auth provider does not support access rules")
+	}
+	for _, org := range a.Orgs {
+		if member, err := resolver.IsOrgMember(ctx, sess, org); err != nil || member {
+			return member, err
+		}
+	}
+	for _, team := range a.Teams {
+		org, name, _ := strings.Cut(team, "/")
+		if member, err := resolver.IsTeamMember(ctx, sess, org, name); err != nil || member {
+			return member, err
+		}
+	}
+	if a.Permission != PermissionNone {
+		permission, err := resolver.RepoPermission(ctx, sess, owner, repo)
+		if err != nil {
+			return false, err
+		}
+		return permissionLevels[permission] >= permissionLevels[a.Permission], nil
+	}
+	return false, nil
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAccessProvider struct {
+	AuthProvider
+	orgs       map[string]bool
+	teams      map[string]bool
+	permission string
+}
+
+func (f *fakeAccessProvider) RepoPermission(context.Context, *AuthSession, string, string) (string, error) {
+	return f.permission, nil
+}
+
+func (f *fakeAccessProvider) IsOrgMember(_ context.Context, _ *AuthSession, org string) (bool, error) {
+	return f.orgs[org], nil
+}
+
+func (f *fakeAccessProvider) IsTeamMember(_ context.Context, _ *AuthSession, org, team string) (bool, error) {
+	return f.teams[org+"/"+team], nil
+}
+
+func TestPageAccessNormalize(t *testing.T) {
+	access := &PageAccess{Teams: []string{" docs/writers "}, Permission: " Write "}
+	require.NoError(t, access.Normalize())
+	assert.Equal(t, []string{"docs/writers"}, access.Teams)
+	assert.Equal(t, PermissionWrite, access.Permission)
+
+	for _, invalid := range []*PageAccess{
+		{},
+		{Teams: []string{"writers"}},
+		{Users: []string{" "}},
+		{Permission: "owner"},
+	} {
+		assert.Error(t, invalid.Normalize(), "%+v", invalid)
+	}
+}
+
+func TestPageAccessAllows(t *testing.T) {
+	ctx := context.Background()
+	sess := &AuthSession{Identity: AuthIdentity{Subject: "1", Name: "Dragon"}}
+	provider := &fakeAccessProvider{
+		orgs:       map[string]bool{"docs": true},
+		teams:      map[string]bool{"docs/writers": true},
+		permission: PermissionRead,
+	}
+	tests := []struct {
+		access  PageAccess
+		allowed bool
+	}{
+		{access: PageAccess{Users: []string{"dragon"}}, allowed: true},
+		{access: PageAccess{Users: []string{"other"}}, allowed: false},
+		{access: PageAccess{Orgs: []string{"docs"}}, allowed: true},
+		{access: PageAccess{Teams: []string{"docs/readers"}}, allowed: false},
+		{access: PageAccess{Teams: []string{"docs/writers"}}, allowed: true},
+		{access: PageAccess{Permission: PermissionRead}, allowed: true},
+		{access: PageAccess{Permission: PermissionWrite}, allowed: false},
+	}
+	for _, tt := range tests {
+		allowed, err := tt.access.Allows(ctx, provider, sess, "docs", "handbook")
+		require.NoError(t, err)
+		assert.Equal(t, tt.allowed, allowed, "%+v", tt.access)
+	}
+
+	// 仅按用户名判断时不要求 AccessProvider
+	allowed, err := (&PageAccess{Users: []string{"dragon"}}).Allows(ctx, nil, sess, "docs", "handbook")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	_, err = (&PageAccess{Orgs: []string{"docs"}}).Allows(ctx, nil, sess, "docs", "handbook")
+	assert.Error(t, err)
+}
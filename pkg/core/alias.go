@@ -5,10 +5,17 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/pkg/errors"
+	"golang.org/x/net/idna"
 	"gopkg.d7z.net/middleware/kv"
 )
 
+// wildcardPrefix 通配符别名前缀，例如 *.docs.example.com
+const wildcardPrefix = "*."
+
 type Alias struct {
 	Owner string `json:"owner"`
 	Repo  string `json:"repo"`
@@ -34,6 +41,27 @@ func (a *DomainAlias) Query(ctx context.Context, domain string) (*Alias, error)
 	return rel, nil
 }
 
+// Match 查询域名对应的仓库，精确绑定优先，其次按最长后缀匹配通配符绑定，
+// 返回通配符匹配到的子域名部分 (精确匹配时为空)
+func (a *DomainAlias) Match(ctx context.Context, domain string) (*Alias, string, error) {
+	alias, err := a.Query(ctx, domain)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return alias, "", err
+	}
+	labels := strings.Split(domain, ".")
+	// 从最长后缀开始，后缀至少保留两级
+	for i := 1; i < len(labels)-1; i++ {
+		alias, err = a.Query(ctx, wildcardPrefix+strings.Join(labels[i:], "."))
+		if err == nil {
+			return alias, strings.Join(labels[:i], "."), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+	return nil, "", os.ErrNotExist
+}
+
 func (a *DomainAlias) Bind(ctx context.Context, domains []string, owner, repo string) error {
 	rKey := base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%s/%s", owner, repo)))
 
@@ -97,3 +125,41 @@ func (a *DomainAlias) Unbind(ctx context.Context, domain string) error {
 	_, err := a.config.Delete(ctx, domain)
 	return err
 }
+
+// NormalizeDomain 将域名转换为小写的 punycode 形式，保留通配符前缀
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	wildcard := strings.HasPrefix(domain, wildcardPrefix)
+	if wildcard {
+		domain = strings.TrimPrefix(domain, wildcardPrefix)
+	}
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", err
+	}
+	if wildcard {
+		return wildcardPrefix + ascii, nil
+	}
+	return ascii, nil
+}
+
+// IsWildcardDomain 判断别名是否为通配符别名
+func IsWildcardDomain(domain string) bool {
+	return strings.HasPrefix(domain, wildcardPrefix)
+}
+
+// MatchAliasDomain 判断域名是否命中别名列表 (含通配符别名)
+func MatchAliasDomain(aliases []string, domain string) bool {
+	for _, item := range aliases {
+		if item == domain {
+			return true
+		}
+		if IsWildcardDomain(item) {
+			suffix := strings.TrimPrefix(item, "*")
+			if strings.HasSuffix(domain, suffix) && len(domain) > len(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -14,6 +14,10 @@ const (
 	AuthPathLogin    = "/.pages/auth/login"
 	AuthPathCallback = "/.pages/auth/callback"
 	AuthPathLogout   = "/.pages/auth/logout"
+	AuthPathSSO      = "/.pages/auth/sso"
+	AuthPathSessions = "/.pages/auth/sessions"
+	AuthPathMe       = "/.pages/auth/me"
+	AuthPathShare    = "/.pages/auth/share"
 )
 
 type AuthProvider interface {
@@ -23,6 +27,16 @@ type AuthProvider interface {
 	Logout(ctx context.Context, sess *AuthSession) error
 }
 
+// RepoWriteAuthorizer 可选接口，判断会话是否拥有仓库写权限
+type RepoWriteAuthorizer interface {
+	AuthorizeRepoWrite(ctx context.Context, sess *AuthSession, owner, repo string) (bool, error)
+}
+
+// TokenAuthProvider 可选接口，校验 Authorization 请求头携带的访问令牌，令牌无效时返回 false
+type TokenAuthProvider interface {
+	AuthenticateToken(ctx context.Context, token string) (*AuthSession, bool, error)
+}
+
 type AuthIdentity struct {
 	Subject string `json:"subject"`
 	Name    string `json:"name"`
@@ -33,6 +47,12 @@ type AuthSession struct {
 	Identity AuthIdentity    `json:"identity"`
 	Private  json.RawMessage `json:"private"`
 	ExpireAt time.Time       `json:"expire_at"`
+	Parent   string          `json:"parent,omitempty"` // 跨域登录时签发票据的统一登录会话
+
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	LastSeen  time.Time `json:"last_seen,omitempty"`
+	Host      string    `json:"host,omitempty"`       // 登录时的域名
+	UserAgent string    `json:"user_agent,omitempty"` // 登录时的 User-Agent
 }
 
 type AuthInfo struct {
@@ -40,6 +60,24 @@ type AuthInfo struct {
 	Identity      *AuthIdentity `json:"identity,omitempty"`
 }
 
+// AuthMe 登录状态接口的响应，供静态页面脚本判断当前用户及其对页面仓库的权限；
+// 仓库信息仅在可访问时返回
+type AuthMe struct {
+	AuthInfo
+	Owner      string `json:"owner,omitempty"`
+	Repo       string `json:"repo,omitempty"`
+	Permission string `json:"permission,omitempty"`
+	Access     bool   `json:"access"`
+	LoginURL   string `json:"login_url"`
+	LogoutURL  string `json:"logout_url"`
+}
+
+// authToken 令牌校验结果缓存，无效令牌同样缓存
+type authToken struct {
+	Valid   bool        `json:"valid"`
+	Session AuthSession `json:"session"`
+}
+
 type AuthState struct {
 	ReturnTo string    `json:"return_to"`
 	ExpireAt time.Time `json:"expire_at"`
@@ -50,9 +88,15 @@ type AuthServiceConfig struct {
 	CookieDomain   string
 	CookieSecure   bool
 	CookieSameSite http.SameSite
-	SessionTTL     time.Duration
+	SessionTTL     time.Duration // 会话有效期，活动时滑动续期
+	SessionMaxTTL  time.Duration // 会话自创建起的最长有效期，0 表示不限制
 	StateTTL       time.Duration
 	AuthzCacheTTL  time.Duration
+	Secret         []byte        // 签名密钥，跨域登录票据使用
+	SSOHost        string        // 统一登录域名，其余不在 Cookie 域内的域名通过一次性票据登录
+	ShareMaxTTL    time.Duration // 分享链接的最长有效期
+	SessionStore   string        // 会话存储方式，SessionStoreKV (默认) 或 SessionStoreCookie
+	SessionKeys    [][]byte      // Cookie 会话加密密钥，首个用于加密，其余仅用于解密；为空时使用 Secret
 	OnUnauthorized func(w http.ResponseWriter, r *http.Request, err error)
 	OnForbidden    func(w http.ResponseWriter, r *http.Request, err error)
 	OnMethodDenied func(w http.ResponseWriter, r *http.Request, err error)
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"gopkg.d7z.net/middleware/kv"
+)
+
+const (
+	// SessionStoreKV 会话保存在 KV 中，Cookie 仅携带会话 ID
+	SessionStoreKV = "kv"
+	// SessionStoreCookie 会话加密后保存在 Cookie 中，KV 仅保存撤销列表与会话列表使用的记录
+	SessionStoreCookie = "cookie"
+
+	sealedSessionPrefix = "v1."
+	// sealedSessionMax 浏览器对单个 Cookie 的长度限制约为 4KB
+	sealedSessionMax = 4000
+	sealKeyIDSize    = 4
+	// revocationRefresh 撤销列表的本地缓存时间，其他实例撤销的会话最多延迟该时长失效
+	revocationRefresh = 10 * time.Second
+	revocationKey     = "list"
+)
+
+var errSessionTooLarge = errors.New("sealed session exceeds cookie size limit")
+
+// sessionSealer 使用 AES-GCM 加密会话，首个密钥用于加密，全部密钥均可解密以支持轮换
+type sessionSealer struct {
+	ids   [][]byte
+	aeads []cipher.AEAD
+}
+
+// newSessionSealer 由任意长度的密钥派生 AES-256 密钥，keys 不能为空
+func newSessionSealer(keys [][]byte) *sessionSealer {
+	sealer := &sessionSealer{}
+	for _, key := range keys {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte("session-seal"))
+		derived := mac.Sum(nil)
+		// 派生密钥固定为 32 字节，不会出错
+		block, _ := aes.NewCipher(derived)
+		aead, _ := cipher.NewGCM(block)
+		id := sha256.Sum256(derived)
+		sealer.ids = append(sealer.ids, id[:sealKeyIDSize])
+		sealer.aeads = append(sealer.aeads, aead)
+	}
+	return sealer
+}
+
+func (s *sessionSealer) seal(sess *AuthSession) (string, error) {
+	payload, err := json.Marshal(sess)
+	if err != nil {
+		return "", err
+	}
+	aead := s.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := append(append([]byte{}, s.ids[0]...), nonce...)
+	data = aead.Seal(data, nonce, payload, s.ids[0])
+	value := sealedSessionPrefix + base64.RawURLEncoding.EncodeToString(data)
+	if len(value) > sealedSessionMax {
+		return "", errSessionTooLarge
+	}
+	return value, nil
+}
+
+func (s *sessionSealer) open(value string) (*AuthSession, bool) {
+	if len(value) <= len(sealedSessionPrefix) || value[:len(sealedSessionPrefix)] != sealedSessionPrefix {
+		return nil, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value[len(sealedSessionPrefix):])
+	if err != nil || len(data) < sealKeyIDSize {
+		return nil, false
+	}
+	id, data := data[:sealKeyIDSize], data[sealKeyIDSize:]
+	for i, aead := range s.aeads {
+		if !hmac.Equal(id, s.ids[i]) || len(data) < aead.NonceSize() {
+			continue
+		}
+		payload, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], id)
+		if err != nil {
+			return nil, false
+		}
+		var sess AuthSession
+		if err = json.Unmarshal(payload, &sess); err != nil {
+			return nil, false
+		}
+		return &sess, true
+	}
+	return nil, false
+}
+
+// sessionRevocations 已撤销的 Cookie 会话，以会话摘要到过期时间的映射保存在单个 KV 键中，
+// 本地缓存定期刷新，避免每个请求都读取 KV
+type sessionRevocations struct {
+	index *kvIndex
+
+	mu       sync.RWMutex
+	ids      map[string]int64
+	loadedAt time.Time
+}
+
+func newSessionRevocations(store kv.KV) *sessionRevocations {
+	return &sessionRevocations{index: newKVIndex(store, "session-revoked")}
+}
+
+// Revoked 判断会话是否已撤销
+func (r *sessionRevocations) Revoked(ctx context.Context, id string) (bool, error) {
+	r.mu.RLock()
+	ids, fresh := r.ids, time.Since(r.loadedAt) < revocationRefresh
+	r.mu.RUnlock()
+	if !fresh {
+		loaded, err := r.index.Load(ctx, revocationKey)
+		if err != nil {
+			return false, err
+		}
+		r.mu.Lock()
+		r.ids, r.loadedAt = loaded, time.Now()
+		r.mu.Unlock()
+		ids = loaded
+	}
+	_, ok := ids[sessionHandle(id)]
+	return ok, nil
+}
+
+// Revoke 将会话加入撤销列表直到 expireAt，同时清理已过期的条目
+func (r *sessionRevocations) Revoke(ctx context.Context, expireAt time.Time, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	result, err := r.index.Update(ctx, revocationKey, func(entries map[string]int64) {
+		for _, id := range ids {
+			entries[sessionHandle(id)] = expireAt.Unix()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// 本实例撤销的会话立即生效
+	r.mu.Lock()
+	r.ids, r.loadedAt = result, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// openSession 解密 Cookie 中的会话并校验有效期与撤销列表
+func (s *AuthService) openSession(ctx context.Context, value string) (*AuthSession, bool, error) {
+	sess, ok := s.sealer.open(value)
+	if !ok || time.Now().After(sess.ExpireAt) {
+		return nil, false, nil
+	}
+	revoked, err := s.revocations.Revoked(ctx, sess.ID)
+	if err != nil || revoked {
+		return nil, false, err
+	}
+	return sess, true, nil
+}
+
+// deleteSessions 删除会话记录，Cookie 会话另外加入撤销列表；
+// 撤销后的会话无法续期，有效期不会超过 SessionTTL
+func (s *AuthService) deleteSessions(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		if err := s.sessions.Delete(ctx, id); err != nil {
+			return err
+		}
+	}
+	if s.sealer == nil {
+		return nil
+	}
+	return s.revocations.Revoke(ctx, time.Now().Add(s.config.SessionTTL), ids...)
+}
+
+// sessionCookieValue 返回会话 Cookie 的值
+func (s *AuthService) sessionCookieValue(sess *AuthSession) (string, error) {
+	if s.sealer == nil {
+		return sess.ID, nil
+	}
+	return s.sealer.seal(sess)
+}
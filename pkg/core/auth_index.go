@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"time"
+
+	"gopkg.d7z.net/middleware/kv"
+)
+
+// kvIndex 以单个 KV 键保存的索引，条目映射到其过期时间 (Unix 秒)；
+// 多个实例可能同时修改同一索引，更新以 CAS 完成，并顺带清理已过期的条目
+type kvIndex struct {
+	store kv.KV
+}
+
+func newKVIndex(store kv.KV, prefix string) *kvIndex {
+	return &kvIndex{store: store.Child(prefix)}
+}
+
+// Load 返回索引中未过期的条目
+func (i *kvIndex) Load(ctx context.Context, key string) (map[string]int64, error) {
+	_, entries, err := i.load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	pruneIndex(entries)
+	return entries, nil
+}
+
+// Update 修改索引并返回修改后的条目，写入冲突时重新读取后重试
+func (i *kvIndex) Update(ctx context.Context, key string, update func(entries map[string]int64)) (map[string]int64, error) {
+	for {
+		raw, entries, err := i.load(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		pruneIndex(entries)
+		update(entries)
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return nil, err
+		}
+		var success bool
+		if raw != nil {
+			success, err = i.store.CompareAndSwap(ctx, key, *raw, string(data))
+		} else {
+			success, err = i.store.PutIfNotExists(ctx, key, string(data), kv.TTLKeep)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if success {
+			return entries, nil
+		}
+	}
+}
+
+// Delete 删除整个索引
+func (i *kvIndex) Delete(ctx context.Context, key string) error {
+	_, err := i.store.Delete(ctx, key)
+	return err
+}
+
+// load 返回索引原始内容与解析后的条目，索引不存在时原始内容为 nil
+func (i *kvIndex) load(ctx context.Context, key string) (*string, map[string]int64, error) {
+	entries := map[string]int64{}
+	raw, err := i.store.Get(ctx, key)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, entries, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// 损坏的索引按空索引覆盖
+	_ = json.Unmarshal([]byte(raw), &entries)
+	return &raw, entries, nil
+}
+
+func pruneIndex(entries map[string]int64) {
+	now := time.Now().Unix()
+	maps.DeleteFunc(entries, func(_ string, expire int64) bool {
+		return expire <= now
+	})
+}
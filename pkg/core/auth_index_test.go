@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+func TestKVIndexConcurrentUpdate(t *testing.T) {
+	store, err := kv.NewMemory("")
+	require.NoError(t, err)
+	index := newKVIndex(store, "index")
+	ctx := context.Background()
+	expireAt := time.Now().Add(time.Hour).Unix()
+
+	// 并发写入不会丢失其他实例加入的条目
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := index.Update(ctx, "u1", func(entries map[string]int64) {
+				entries["s"+strconv.Itoa(i)] = expireAt
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	entries, err := index.Load(ctx, "u1")
+	require.NoError(t, err)
+	assert.Len(t, entries, 32)
+
+	// 已过期的条目在读取与更新时清理
+	_, err = index.Update(ctx, "u1", func(entries map[string]int64) {
+		entries["expired"] = time.Now().Add(-time.Second).Unix()
+		delete(entries, "s0")
+	})
+	require.NoError(t, err)
+	entries, err = index.Load(ctx, "u1")
+	require.NoError(t, err)
+	assert.Len(t, entries, 31)
+	assert.NotContains(t, entries, "expired")
+
+	entries, err = index.Load(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
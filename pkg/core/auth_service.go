@@ -2,6 +2,9 @@ package core
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"net/url"
@@ -20,8 +23,28 @@ type AuthService struct {
 	sessions *tools.KVCache[AuthSession]
 	states   *tools.KVCache[AuthState]
 	authz    *tools.KVCache[bool]
+	// permissions 仓库权限缓存，与 authz 共用索引
+	permissions *tools.KVCache[string]
+	tokens      *tools.KVCache[authToken]
+	tickets     *tools.KVCache[ssoTicket]
+	// children 跨域登录派生的会话索引，userSessions 用户会话索引，authzIndex 用户授权缓存键索引
+	children     *kvIndex
+	userSessions *kvIndex
+	authzIndex   *kvIndex
+
+	// tokenFailures 按客户端统计无效的访问令牌
+	tokenFailures *failureLimiter
+
+	// sealer 非空时会话加密保存在 Cookie 中，revocations 为其撤销列表
+	sealer      *sessionSealer
+	revocations *sessionRevocations
+
+	hostPolicy func(ctx context.Context, host, path string) bool
 }
 
+// authTokenSessionPrefix 令牌会话 ID 前缀，令牌会话不落库也不写入 Cookie
+const authTokenSessionPrefix = "token:"
+
 func NewAuthService(provider AuthProvider, store kv.KV, config AuthServiceConfig) *AuthService {
 	if config.CookieName == "" {
 		config.CookieName = "gitea_pages_session"
@@ -38,6 +61,9 @@ func NewAuthService(provider AuthProvider, store kv.KV, config AuthServiceConfig
 	if config.AuthzCacheTTL == 0 {
 		config.AuthzCacheTTL = 30 * time.Second
 	}
+	if config.ShareMaxTTL == 0 {
+		config.ShareMaxTTL = 7 * 24 * time.Hour
+	}
 	if config.OnUnauthorized == nil {
 		config.OnUnauthorized = func(w http.ResponseWriter, _ *http.Request, err error) {
 			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
@@ -53,13 +79,38 @@ func NewAuthService(provider AuthProvider, store kv.KV, config AuthServiceConfig
 			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		}
 	}
-	return &AuthService{
+	service := &AuthService{
 		provider: provider,
 		config:   config,
 		sessions: tools.NewCache[AuthSession](store, "session", config.SessionTTL),
 		states:   tools.NewCache[AuthState](store, "state", config.StateTTL),
 		authz:    tools.NewCache[bool](store, "authz", config.AuthzCacheTTL),
+
+		permissions: tools.NewCache[string](store, "permission", config.AuthzCacheTTL),
+		tokens:      tools.NewCache[authToken](store, "token", config.AuthzCacheTTL),
+		tickets:     tools.NewCache[ssoTicket](store, "sso-ticket", ssoTicketTTL),
+
+		children:     newKVIndex(store, "sso-children"),
+		userSessions: newKVIndex(store, "user-sessions"),
+		authzIndex:   newKVIndex(store, "authz-index"),
+
+		tokenFailures: newFailureLimiter(store.Child("token-failures")),
 	}
+	if config.SessionStore == SessionStoreCookie {
+		keys := config.SessionKeys
+		if len(keys) == 0 && len(config.Secret) > 0 {
+			keys = [][]byte{config.Secret}
+		}
+		if len(keys) == 0 {
+			// 未配置密钥时使用随机密钥，重启后会话失效
+			key := make([]byte, 32)
+			_, _ = rand.Read(key)
+			keys = [][]byte{key}
+		}
+		service.sealer = newSessionSealer(keys)
+		service.revocations = newSessionRevocations(store)
+	}
+	return service
 }
 
 func (s *AuthService) Handle(w http.ResponseWriter, req *http.Request) error {
@@ -70,51 +121,225 @@ func (s *AuthService) Handle(w http.ResponseWriter, req *http.Request) error {
 		return s.handleCallback(w, req)
 	case AuthPathLogout:
 		return s.handleLogout(w, req)
+	case AuthPathSSO:
+		return s.handleSSO(w, req)
+	case AuthPathSessions:
+		return s.handleSessions(w, req)
 	default:
 		http.NotFound(w, req)
 		return nil
 	}
 }
 
+// RequireRepoAccess 私有页面要求登录并拥有访问权限，或携带覆盖当前路径的有效分享令牌
 func (s *AuthService) RequireRepoAccess(w http.ResponseWriter, req *http.Request, page *PageContent) (bool, error) {
 	if !page.Private {
 		return true, nil
 	}
+	allowed, redirected := s.allowShare(w, req, page)
+	if allowed || redirected {
+		return allowed, nil
+	}
+	return s.RequireAccess(w, req, page, nil)
+}
+
+// RequireAccess 要求请求已登录并满足访问规则，access 为空时按页面规则或仓库读取权限判断；
+// 未通过时写入登录跳转或 403 响应并返回 false
+func (s *AuthService) RequireAccess(w http.ResponseWriter, req *http.Request, page *PageContent, access *PageAccess) (bool, error) {
+	sess, ok, err := s.requireSession(w, req)
+	if err != nil || !ok {
+		return false, err
+	}
+	*req = *req.WithContext(ContextWithAuthSession(req.Context(), sess))
+
+	meta := page.PageMetaContent
+	if access != nil {
+		meta = &PageMetaContent{Access: access}
+	}
+	authorized, err := s.CanAccessPage(req.Context(), sess, page.Owner, page.Repo, meta)
+	if err != nil {
+		return false, err
+	}
+	if !authorized {
+		s.config.OnForbidden(w, req, errors.New(http.StatusText(http.StatusForbidden)))
+		return false, nil
+	}
+	return true, nil
+}
+
+// CanAccessRepo 判断会话是否可访问仓库，结果按 AuthzCacheTTL 缓存
+func (s *AuthService) CanAccessRepo(ctx context.Context, sess *AuthSession, owner, repo string) (bool, error) {
+	return s.authorize(ctx, sess, owner, repo, "", s.provider.AuthorizeRepo)
+}
+
+// CanAccessPage 判断会话是否可访问页面，声明访问规则时按规则判断，否则按仓库读取权限判断
+func (s *AuthService) CanAccessPage(ctx context.Context, sess *AuthSession, owner, repo string, meta *PageMetaContent) (bool, error) {
+	if meta == nil || meta.Access == nil {
+		return s.CanAccessRepo(ctx, sess, owner, repo)
+	}
+	// 缓存按规则内容区分，规则变更后立即生效
+	return s.authorize(ctx, sess, owner, repo, "access@"+meta.Access.Digest(), func(ctx context.Context, sess *AuthSession, owner, repo string) (bool, error) {
+		return meta.Access.Allows(ctx, s.provider, sess, owner, repo)
+	})
+}
+
+// CanWriteRepo 判断会话是否拥有仓库写权限，提供方未实现 RepoWriteAuthorizer 时视为无权限
+func (s *AuthService) CanWriteRepo(ctx context.Context, sess *AuthSession, owner, repo string) (bool, error) {
+	authorizer, ok := s.provider.(RepoWriteAuthorizer)
+	if !ok {
+		return false, nil
+	}
+	return s.authorize(ctx, sess, owner, repo, "write", authorizer.AuthorizeRepoWrite)
+}
+
+// RepoPermission 返回会话在仓库上的权限，结果按 AuthzCacheTTL 缓存；
+// 提供方未实现 AccessProvider 时按读写授权推断
+func (s *AuthService) RepoPermission(ctx context.Context, sess *AuthSession, owner, repo string) (string, error) {
+	resolver, ok := s.provider.(AccessProvider)
+	if !ok {
+		if writable, err := s.CanWriteRepo(ctx, sess, owner, repo); err != nil || writable {
+			return PermissionWrite, err
+		}
+		if readable, err := s.CanAccessRepo(ctx, sess, owner, repo); err != nil || readable {
+			return PermissionRead, err
+		}
+		return PermissionNone, nil
+	}
+	key := authzKey(sess, owner, repo, "permission")
+	if permission, ok, err := s.permissions.Load(ctx, key); err != nil || ok {
+		return permission, err
+	}
+	provided, ok, err := s.providerSession(ctx, sess)
+	if err != nil || !ok {
+		return PermissionNone, err
+	}
+	permission, err := resolver.RepoPermission(ctx, provided, owner, repo)
+	if err != nil {
+		return PermissionNone, err
+	}
+	if err = s.permissions.Store(ctx, key, permission); err == nil {
+		_ = s.indexAuthz(ctx, sess, key)
+	}
+	return permission, nil
+}
+
+func (s *AuthService) authorize(
+	ctx context.Context,
+	sess *AuthSession,
+	owner, repo, scope string,
+	check func(ctx context.Context, sess *AuthSession, owner, repo string) (bool, error),
+) (bool, error) {
+	allowed, ok, err := s.loadAuthz(ctx, sess, owner, repo, scope)
+	if err != nil || ok {
+		return allowed, err
+	}
+	provided, ok, err := s.providerSession(ctx, sess)
+	if err != nil || !ok {
+		return false, err
+	}
+	allowed, err = check(ctx, provided, owner, repo)
+	if err != nil {
+		return false, err
+	}
+	_ = s.storeAuthz(ctx, sess, owner, repo, scope, allowed)
+	return allowed, nil
+}
 
+// requireSession 优先使用 Authorization 请求头中的令牌，否则读取登录 Cookie；
+// 未登录时写入 401 或登录跳转
+func (s *AuthService) requireSession(w http.ResponseWriter, req *http.Request) (*AuthSession, bool, error) {
+	if token, ok := s.requestToken(req); ok {
+		sess, ok, err := s.loadTokenSession(req, token)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gitea-pages"`)
+			s.config.OnUnauthorized(w, req, errors.New("invalid access token"))
+			return nil, false, nil
+		}
+		return sess, true, nil
+	}
 	_, hadCookieErr := req.Cookie(s.config.CookieName)
 	hadCookie := hadCookieErr == nil
 	sess, ok, err := s.loadSession(req.Context(), req)
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	if !ok {
 		if hadCookie {
-			s.clearSessionCookie(w)
+			s.clearSessionCookie(w, req)
 		}
 		http.Redirect(w, req, AuthPathLogin+"?return_to="+url.QueryEscape(req.URL.RequestURI()), http.StatusFound)
-		return false, nil
+		return nil, false, nil
 	}
-	*req = *req.WithContext(ContextWithAuthSession(req.Context(), sess))
+	s.touchSession(w, req, sess)
+	return sess, true, nil
+}
+
+// requestToken 读取 Bearer / token 认证，或密码为访问令牌的 Basic 认证，提供方不支持令牌时忽略；
+// 上游代理或浏览器缓存的普通 Basic 凭据不会作为令牌发送至认证服务
+func (s *AuthService) requestToken(req *http.Request) (string, bool) {
+	if _, ok := s.provider.(TokenAuthProvider); !ok {
+		return "", false
+	}
+	if _, password, ok := req.BasicAuth(); ok {
+		return password, isAccessToken(password)
+	}
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || (!strings.EqualFold(scheme, "Bearer") && !strings.EqualFold(scheme, "token")) {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
 
-	authorized, ok, err := s.loadAuthz(req.Context(), sess, page.Owner, page.Repo)
+// isAccessToken 判断 Basic 密码是否为 Gitea 访问令牌 (40 位十六进制)
+func isAccessToken(password string) bool {
+	if len(password) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(password)
+	return err == nil
+}
+
+// loadTokenSession 校验访问令牌，有效结果按令牌摘要缓存 AuthzCacheTTL；
+// 无效令牌不写入缓存，同一客户端连续失败过多时在窗口内不再校验
+func (s *AuthService) loadTokenSession(req *http.Request, token string) (*AuthSession, bool, error) {
+	ctx := req.Context()
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	cached, ok, err := s.tokens.Load(ctx, key)
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	if !ok {
-		authorized, err = s.provider.AuthorizeRepo(req.Context(), sess, page.Owner, page.Repo)
+		client := RequestInfoFromRequest(req).ClientIP
+		if allowed, err := s.tokenFailures.Allow(ctx, client); err != nil || !allowed {
+			return nil, false, err
+		}
+		sess, valid, err := s.provider.(TokenAuthProvider).AuthenticateToken(ctx, token)
 		if err != nil {
-			return false, err
+			return nil, false, err
+		}
+		if !valid || sess == nil {
+			return nil, false, s.tokenFailures.Fail(ctx, client)
+		}
+		cached = authToken{Valid: true, Session: *sess}
+		cached.Session.ID = authTokenSessionPrefix + key
+		if cached.Session.ExpireAt.IsZero() {
+			cached.Session.ExpireAt = time.Now().Add(s.config.AuthzCacheTTL)
 		}
-		_ = s.storeAuthz(req.Context(), sess, page.Owner, page.Repo, authorized)
+		_ = s.tokens.Store(ctx, key, cached)
 	}
-	if !authorized {
-		s.config.OnForbidden(w, req, errors.New(http.StatusText(http.StatusForbidden)))
-		return false, nil
+	if !cached.Valid || time.Now().After(cached.Session.ExpireAt) {
+		return nil, false, nil
 	}
-	return true, nil
+	return &cached.Session, true, nil
 }
 
-func (s *AuthService) AttachAuth(req *http.Request) error {
+// AttachAuth 将请求携带的登录会话写入请求上下文，w 非空时按活动续期会话
+func (s *AuthService) AttachAuth(w http.ResponseWriter, req *http.Request) error {
 	sess, ok, err := s.loadSession(req.Context(), req)
 	if err != nil {
 		return err
@@ -122,12 +347,22 @@ func (s *AuthService) AttachAuth(req *http.Request) error {
 	if !ok {
 		return nil
 	}
+	if w != nil {
+		s.touchSession(w, req, sess)
+	}
 	*req = *req.WithContext(ContextWithAuthSession(req.Context(), sess))
 	return nil
 }
 
 func (s *AuthService) handleLogin(w http.ResponseWriter, req *http.Request) error {
-	returnTo := sanitizeReturnTo(req.URL.Query().Get("return_to"))
+	returnTo := safeReturnTo(req, req.URL.Query().Get("return_to"))
+	// 跨域登录的票据仅签发给发起登录的域名
+	host := requestHost(req)
+	if s.needsSSO(host) {
+		// 登录 Cookie 无法覆盖该域名，转到统一登录域名签发票据
+		http.Redirect(w, req, s.ssoURL(req, s.config.SSOHost, url.Values{"host": {host}, "return_to": {returnTo}}), http.StatusFound)
+		return nil
+	}
 	stateID := uuid.NewString()
 	if err := s.states.Store(req.Context(), stateID, AuthState{
 		ReturnTo: returnTo,
@@ -170,19 +405,12 @@ func (s *AuthService) handleCallback(w http.ResponseWriter, req *http.Request) e
 	if sess.ExpireAt.IsZero() {
 		sess.ExpireAt = time.Now().Add(s.config.SessionTTL)
 	}
-	if err = s.sessions.Store(req.Context(), sess.ID, *sess); err != nil {
+	if err = s.storeSession(req.Context(), req, sess); err != nil {
+		return err
+	}
+	if err = s.setSessionCookie(w, sess, s.config.CookieDomain); err != nil {
 		return err
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     s.config.CookieName,
-		Value:    sess.ID,
-		Path:     "/",
-		Domain:   s.config.CookieDomain,
-		HttpOnly: true,
-		Secure:   s.config.CookieSecure,
-		SameSite: s.config.CookieSameSite,
-		Expires:  sess.ExpireAt,
-	})
 	http.Redirect(w, req, state.ReturnTo, http.StatusFound)
 	return nil
 }
@@ -197,10 +425,14 @@ func (s *AuthService) handleLogout(w http.ResponseWriter, req *http.Request) err
 		return err
 	}
 	if ok {
-		_ = s.provider.Logout(req.Context(), sess)
-		_ = s.sessions.Delete(req.Context(), sess.ID)
+		if provided, ok, err := s.providerSession(req.Context(), sess); err == nil && ok {
+			_ = s.provider.Logout(req.Context(), provided)
+		}
+		if err = s.revokeSSO(req.Context(), sess); err != nil {
+			return err
+		}
 	}
-	s.clearSessionCookie(w)
+	s.clearSessionCookie(w, req)
 	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
@@ -213,30 +445,90 @@ func (s *AuthService) loadSession(ctx context.Context, req *http.Request) (*Auth
 		}
 		return nil, false, err
 	}
-	sess, ok, err := s.sessions.Load(ctx, cookie.Value)
-	if err != nil {
+	var (
+		sess *AuthSession
+		ok   bool
+	)
+	if s.sealer != nil {
+		sess, ok, err = s.openSession(ctx, cookie.Value)
+	} else {
+		sess, ok, err = s.loadSessionByID(ctx, cookie.Value)
+	}
+	if err != nil || !ok {
 		return nil, false, err
 	}
-	if !ok || time.Now().After(sess.ExpireAt) {
+	// 跨域登录派生的会话仅对兑换票据的域名有效
+	if sess.Parent != "" && sess.Host != requestHost(req) {
 		return nil, false, nil
 	}
-	return &sess, true, nil
+	return sess, true, nil
+}
+
+func (s *AuthService) loadAuthz(ctx context.Context, sess *AuthSession, owner, repo, scope string) (bool, bool, error) {
+	return s.authz.Load(ctx, authzKey(sess, owner, repo, scope))
+}
+
+func (s *AuthService) storeAuthz(ctx context.Context, sess *AuthSession, owner, repo, scope string, allowed bool) error {
+	key := authzKey(sess, owner, repo, scope)
+	if err := s.authz.Store(ctx, key, allowed); err != nil {
+		return err
+	}
+	return s.indexAuthz(ctx, sess, key)
 }
 
-func (s *AuthService) loadAuthz(ctx context.Context, sess *AuthSession, owner, repo string) (bool, bool, error) {
-	return s.authz.Load(ctx, sess.Identity.Subject+"/"+owner+"/"+repo)
+// indexAuthz 记录授权缓存键，撤销会话时一并清理
+func (s *AuthService) indexAuthz(ctx context.Context, sess *AuthSession, key string) error {
+	expireAt := time.Now().Add(s.config.AuthzCacheTTL).Unix()
+	_, err := s.authzIndex.Update(ctx, sess.Identity.Subject, func(entries map[string]int64) {
+		entries[key] = expireAt
+	})
+	return err
+}
+
+// authzKey 授权缓存键，非读取权限以 #scope 后缀区分；
+// 令牌的权限范围可能小于用户本身，令牌会话按令牌单独缓存
+func authzKey(sess *AuthSession, owner, repo, scope string) string {
+	subject := sess.Identity.Subject
+	if strings.HasPrefix(sess.ID, authTokenSessionPrefix) {
+		subject = sess.ID
+	}
+	key := subject + "/" + owner + "/" + repo
+	if scope != "" {
+		key += "#" + scope
+	}
+	return key
 }
 
-func (s *AuthService) storeAuthz(ctx context.Context, sess *AuthSession, owner, repo string, allowed bool) error {
-	return s.authz.Store(ctx, sess.Identity.Subject+"/"+owner+"/"+repo, allowed)
+// setSessionCookie 写入会话 Cookie，domain 为空时仅对当前域名有效
+func (s *AuthService) setSessionCookie(w http.ResponseWriter, sess *AuthSession, domain string) error {
+	value, err := s.sessionCookieValue(sess)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.config.CookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   domain,
+		HttpOnly: true,
+		Secure:   s.config.CookieSecure,
+		SameSite: s.config.CookieSameSite,
+		Expires:  sess.ExpireAt,
+	})
+	return nil
 }
 
-func (s *AuthService) clearSessionCookie(w http.ResponseWriter) {
+func (s *AuthService) clearSessionCookie(w http.ResponseWriter, req *http.Request) {
+	domain := s.config.CookieDomain
+	if !s.cookieCovers(requestHost(req)) {
+		// 跨域登录的会话 Cookie 仅对当前域名有效
+		domain = ""
+	}
 	http.SetCookie(w, &http.Cookie{
 		Name:     s.config.CookieName,
 		Value:    "",
 		Path:     "/",
-		Domain:   s.config.CookieDomain,
+		Domain:   domain,
 		HttpOnly: true,
 		Secure:   s.config.CookieSecure,
 		SameSite: s.config.CookieSameSite,
@@ -245,12 +537,26 @@ func (s *AuthService) clearSessionCookie(w http.ResponseWriter) {
 	})
 }
 
+// sanitizeReturnTo 仅允许站内相对路径
 func sanitizeReturnTo(returnTo string) string {
 	if returnTo == "" {
 		return "/"
 	}
-	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
 		return "/"
 	}
 	return returnTo
 }
+
+// safeReturnTo 在站内相对路径之外，允许指向当前请求域名的 https 地址，返回其站内路径；
+// 其他域名的地址不作为返回目标，需在该域名发起登录
+func safeReturnTo(req *http.Request, returnTo string) string {
+	if strings.HasPrefix(returnTo, "/") || returnTo == "" {
+		return sanitizeReturnTo(returnTo)
+	}
+	target, err := url.Parse(returnTo)
+	if err != nil || target.Scheme != "https" || target.User != nil || !strings.EqualFold(target.Host, req.Host) {
+		return "/"
+	}
+	return sanitizeReturnTo(target.RequestURI())
+}
@@ -0,0 +1,284 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// sessionTouchInterval 最近活动时间的最小记录间隔，避免每个请求都写入会话
+	sessionTouchInterval = time.Minute
+	sessionUserAgentMax  = 256
+)
+
+// SessionInfo 会话列表中对外展示的信息，不包含会话 ID 本身
+type SessionInfo struct {
+	ID        string    `json:"id"` // 会话 ID 摘要，用于撤销
+	Host      string    `json:"host,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpireAt  time.Time `json:"expire_at"`
+	Current   bool      `json:"current"`
+}
+
+// storeSession 保存新会话并加入用户会话索引
+func (s *AuthService) storeSession(ctx context.Context, req *http.Request, sess *AuthSession) error {
+	now := time.Now()
+	sess.CreatedAt = now
+	sess.LastSeen = now
+	sess.Host = requestHost(req)
+	sess.UserAgent = truncateString(req.UserAgent(), sessionUserAgentMax)
+	if err := s.sessions.Store(ctx, sess.ID, s.sessionRecord(sess)); err != nil {
+		return err
+	}
+	return s.indexSession(ctx, sess)
+}
+
+// sessionRecord 返回写入 KV 的会话记录；Cookie 会话的记录仅供会话列表与撤销使用，
+// 不保存提供方数据
+func (s *AuthService) sessionRecord(sess *AuthSession) AuthSession {
+	if s.sealer == nil {
+		return *sess
+	}
+	return AuthSession{
+		ID:        sess.ID,
+		Identity:  AuthIdentity{Subject: sess.Identity.Subject},
+		ExpireAt:  sess.ExpireAt,
+		Parent:    sess.Parent,
+		CreatedAt: sess.CreatedAt,
+		LastSeen:  sess.LastSeen,
+		Host:      sess.Host,
+		UserAgent: sess.UserAgent,
+	}
+}
+
+// touchSession 记录会话活动，剩余有效期不足一半时按 SessionTTL 续期并重新下发 Cookie；
+// 续期不超过 SessionMaxTTL 限定的最长有效期。Cookie 会话仅在续期时写入，最近活动时间随续期更新
+func (s *AuthService) touchSession(w http.ResponseWriter, req *http.Request, sess *AuthSession) {
+	now := time.Now()
+	renew := sess.ExpireAt.Sub(now) < s.config.SessionTTL/2
+	if !renew && (s.sealer != nil || now.Sub(sess.LastSeen) < sessionTouchInterval) {
+		return
+	}
+	sess.LastSeen = now
+	if renew {
+		expireAt := now.Add(s.config.SessionTTL)
+		if s.config.SessionMaxTTL > 0 && !sess.CreatedAt.IsZero() {
+			expireAt = minTime(expireAt, sess.CreatedAt.Add(s.config.SessionMaxTTL))
+		}
+		renew = expireAt.After(sess.ExpireAt)
+		if renew {
+			sess.ExpireAt = expireAt
+		}
+	}
+	if err := s.sessions.Store(req.Context(), sess.ID, s.sessionRecord(sess)); err != nil {
+		return
+	}
+	if renew {
+		_ = s.indexSession(req.Context(), sess)
+		domain := s.config.CookieDomain
+		if sess.Parent != "" {
+			domain = ""
+		}
+		_ = s.setSessionCookie(w, sess, domain)
+	}
+}
+
+// indexSession 将会话加入用户会话索引，跨域登录派生的会话同时加入统一登录会话的索引；
+// 条目随会话续期更新过期时间
+func (s *AuthService) indexSession(ctx context.Context, sess *AuthSession) error {
+	expireAt := sess.ExpireAt.Unix()
+	if _, err := s.userSessions.Update(ctx, sess.Identity.Subject, func(entries map[string]int64) {
+		entries[sess.ID] = expireAt
+	}); err != nil {
+		return err
+	}
+	if sess.Parent == "" {
+		return nil
+	}
+	_, err := s.children.Update(ctx, sess.Parent, func(entries map[string]int64) {
+		entries[sess.ID] = expireAt
+	})
+	return err
+}
+
+// Sessions 返回用户当前有效的会话
+func (s *AuthService) Sessions(ctx context.Context, subject string) ([]AuthSession, error) {
+	ids, err := s.userSessions.Load(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]AuthSession, 0, len(ids))
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		sess, ok, err := s.loadSessionByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if ok && sess.Identity.Subject == subject {
+			result = append(result, *sess)
+		}
+	}
+	return result, nil
+}
+
+// RevokeSession 撤销会话以及由其跨域登录派生的会话，并清理用户的授权缓存
+func (s *AuthService) RevokeSession(ctx context.Context, sess *AuthSession) error {
+	children, err := s.children.Load(ctx, sess.ID)
+	if err != nil {
+		return err
+	}
+	ids := append(slices.Collect(maps.Keys(children)), sess.ID)
+	if err = s.deleteSessions(ctx, ids...); err != nil {
+		return err
+	}
+	_ = s.children.Delete(ctx, sess.ID)
+	if _, err = s.userSessions.Update(ctx, sess.Identity.Subject, func(entries map[string]int64) {
+		for _, id := range ids {
+			delete(entries, id)
+		}
+	}); err != nil {
+		return err
+	}
+	return s.clearAuthz(ctx, sess.Identity.Subject)
+}
+
+// RevokeSubject 撤销用户的全部会话与授权缓存，供管理员强制下线使用
+func (s *AuthService) RevokeSubject(ctx context.Context, subject string) error {
+	entries, err := s.userSessions.Load(ctx, subject)
+	if err != nil {
+		return err
+	}
+	ids := slices.Collect(maps.Keys(entries))
+	for _, id := range ids {
+		_ = s.children.Delete(ctx, id)
+	}
+	if err = s.deleteSessions(ctx, ids...); err != nil {
+		return err
+	}
+	// 仅移除已撤销的会话，保留撤销期间并发登录的新会话
+	if _, err = s.userSessions.Update(ctx, subject, func(entries map[string]int64) {
+		for _, id := range ids {
+			delete(entries, id)
+		}
+	}); err != nil {
+		return err
+	}
+	return s.clearAuthz(ctx, subject)
+}
+
+// clearAuthz 删除授权缓存索引中记录的条目
+func (s *AuthService) clearAuthz(ctx context.Context, subject string) error {
+	entries, err := s.authzIndex.Load(ctx, subject)
+	if err != nil {
+		return err
+	}
+	for key := range entries {
+		_ = s.authz.Delete(ctx, key)
+		_ = s.permissions.Delete(ctx, key)
+	}
+	_, err = s.authzIndex.Update(ctx, subject, func(current map[string]int64) {
+		for key := range entries {
+			delete(current, key)
+		}
+	})
+	return err
+}
+
+// handleSessions 列出 (GET) 或撤销 (DELETE) 当前用户的会话；
+// DELETE 指定 id 时撤销对应会话，否则撤销当前会话以外的全部会话
+func (s *AuthService) handleSessions(w http.ResponseWriter, req *http.Request) error {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead && req.Method != http.MethodDelete {
+		s.config.OnMethodDenied(w, req, errors.New(http.StatusText(http.StatusMethodNotAllowed)))
+		return nil
+	}
+	current, ok, err := s.loadSession(req.Context(), req)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if !ok {
+		s.config.OnUnauthorized(w, req, errors.New(http.StatusText(http.StatusUnauthorized)))
+		return nil
+	}
+	sessions, err := s.Sessions(req.Context(), current.Identity.Subject)
+	if err != nil {
+		return err
+	}
+	if req.Method == http.MethodDelete {
+		target := req.URL.Query().Get("id")
+		found := false
+		for i := range sessions {
+			sess := &sessions[i]
+			handle := sessionHandle(sess.ID)
+			if (target == "" && sess.ID != current.ID) || handle == target {
+				found = true
+				if err = s.RevokeSession(req.Context(), sess); err != nil {
+					return err
+				}
+			}
+		}
+		if target != "" && !found {
+			http.NotFound(w, req)
+			return nil
+		}
+		if target == sessionHandle(current.ID) {
+			s.clearSessionCookie(w, req)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	result := make([]SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		result = append(result, SessionInfo{
+			ID:        sessionHandle(sess.ID),
+			Host:      sess.Host,
+			UserAgent: sess.UserAgent,
+			CreatedAt: sess.CreatedAt,
+			LastSeen:  sess.LastSeen,
+			ExpireAt:  sess.ExpireAt,
+			Current:   sess.ID == current.ID,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(map[string]any{"sessions": result})
+}
+
+func (s *AuthService) loadSessionByID(ctx context.Context, id string) (*AuthSession, bool, error) {
+	sess, ok, err := s.sessions.Load(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok || time.Now().After(sess.ExpireAt) {
+		return nil, false, nil
+	}
+	return &sess, true, nil
+}
+
+// sessionHandle 会话 ID 的摘要，KV 会话的 ID 即 Cookie 值，不直接对外展示
+func sessionHandle(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+func truncateString(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	return strings.ToValidUTF8(value[:limit], "")
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
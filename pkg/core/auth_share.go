@@ -0,0 +1,259 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// ShareQueryKey 分享链接携带签名令牌的查询参数
+	ShareQueryKey   = "pages_share"
+	shareCookieName = "gitea_pages_share"
+	shareDefaultTTL = 24 * time.Hour
+)
+
+var (
+	errShareDisabled  = errors.New("share links require secret")
+	errShareForbidden = errors.New("share target is not accessible")
+)
+
+// shareClaims 分享令牌内容，令牌仅授权访问仓库内 Prefix 路径及其子路径
+type shareClaims struct {
+	Owner    string `json:"o"`
+	Repo     string `json:"r"`
+	Prefix   string `json:"p"`
+	ExpireAt int64  `json:"e"`
+	IP       string `json:"ip,omitempty"` // 限定的客户端 IP 或网段
+}
+
+// CreateShare 为页面路径前缀签发分享令牌，私有页面要求会话拥有访问权限；
+// ttl 为 0 时使用默认有效期，不超过 ShareMaxTTL
+func (s *AuthService) CreateShare(
+	ctx context.Context,
+	sess *AuthSession,
+	page *PageContent,
+	prefix string,
+	ttl time.Duration,
+	ip string,
+) (string, time.Time, error) {
+	if len(s.config.Secret) == 0 {
+		return "", time.Time{}, errShareDisabled
+	}
+	if page.Private {
+		allowed, err := s.CanAccessPage(ctx, sess, page.Owner, page.Repo, page.PageMetaContent)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if !allowed {
+			return "", time.Time{}, errShareForbidden
+		}
+	}
+	if ttl == 0 {
+		ttl = min(shareDefaultTTL, s.config.ShareMaxTTL)
+	}
+	if ttl < 0 || ttl > s.config.ShareMaxTTL {
+		return "", time.Time{}, errors.New("share ttl must be within " + s.config.ShareMaxTTL.String())
+	}
+	if ip != "" {
+		bound, err := parseShareIP(ip)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		ip = bound.String()
+	}
+	expireAt := time.Now().Add(ttl).Truncate(time.Second)
+	payload, err := json.Marshal(shareClaims{
+		Owner:    page.Owner,
+		Repo:     page.Repo,
+		Prefix:   sharePrefix(prefix),
+		ExpireAt: expireAt.Unix(),
+		IP:       ip,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signShare(encoded), expireAt, nil
+}
+
+// allowShare 校验请求携带的分享令牌，查询参数中的令牌有效时下发限定于分享路径的 Cookie；
+// GET 请求随后跳转到去除令牌的地址，避免令牌经 Referer、日志与历史记录泄露，此时 redirected 为 true
+func (s *AuthService) allowShare(w http.ResponseWriter, req *http.Request, page *PageContent) (allowed, redirected bool) {
+	if len(s.config.Secret) == 0 {
+		return false, false
+	}
+	query := req.URL.Query()
+	if token := query.Get(ShareQueryKey); token != "" {
+		claims, ok := s.verifyShare(req, page, token)
+		if !ok {
+			return false, false
+		}
+		cookiePath := strings.TrimSuffix(page.BasePath, "/") + "/" + claims.Prefix
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareCookieName,
+			Value:    token,
+			Path:     cookiePath,
+			HttpOnly: true,
+			Secure:   s.config.CookieSecure,
+			SameSite: http.SameSiteLaxMode,
+			Expires:  time.Unix(claims.ExpireAt, 0),
+		})
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return true, false
+		}
+		query.Del(ShareQueryKey)
+		target := &url.URL{Path: req.URL.Path, RawPath: req.URL.RawPath, RawQuery: query.Encode()}
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, req, sanitizeReturnTo(target.RequestURI()), http.StatusSeeOther)
+		return false, true
+	}
+	// 不同路径的分享 Cookie 同名共存
+	for _, cookie := range req.Cookies() {
+		if cookie.Name != shareCookieName {
+			continue
+		}
+		if _, ok := s.verifyShare(req, page, cookie.Value); ok {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+func (s *AuthService) verifyShare(req *http.Request, page *PageContent, token string) (*shareClaims, bool) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signShare(encoded))) {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	var claims shareClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, false
+	}
+	if claims.Owner != page.Owner || claims.Repo != page.Repo || time.Now().Unix() >= claims.ExpireAt {
+		return nil, false
+	}
+	if !shareCovers(claims.Prefix, page.Path) {
+		return nil, false
+	}
+	if claims.IP != "" {
+		bound, err := parseShareIP(claims.IP)
+		if err != nil {
+			return nil, false
+		}
+		client, err := netip.ParseAddr(RequestInfoFromRequest(req).ClientIP)
+		if err != nil || !bound.Contains(client.Unmap()) {
+			return nil, false
+		}
+	}
+	return &claims, true
+}
+
+func (s *AuthService) signShare(payload string) string {
+	mac := hmac.New(sha256.New, s.config.Secret)
+	mac.Write([]byte("share\x00" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// HandleShare 为当前用户签发分享链接，target 为查询参数 path 指定的本域名页面地址，page 为其解析出的页面；
+// 可选查询参数 ttl (如 24h) 与 ip (IP 或网段)
+func (s *AuthService) HandleShare(w http.ResponseWriter, req *http.Request, page *PageContent, target string) error {
+	if req.Method != http.MethodPost {
+		s.config.OnMethodDenied(w, req, errors.New(http.StatusText(http.StatusMethodNotAllowed)))
+		return nil
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	var (
+		sess *AuthSession
+		ok   bool
+		err  error
+	)
+	if token, hasToken := s.requestToken(req); hasToken {
+		sess, ok, err = s.loadTokenSession(req, token)
+	} else {
+		sess, ok, err = s.loadSession(req.Context(), req)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.config.OnUnauthorized(w, req, errors.New(http.StatusText(http.StatusUnauthorized)))
+		return nil
+	}
+	if page == nil {
+		http.NotFound(w, req)
+		return nil
+	}
+	query := req.URL.Query()
+	var ttl time.Duration
+	if raw := query.Get("ttl"); raw != "" {
+		if ttl, err = time.ParseDuration(raw); err != nil {
+			http.Error(w, "invalid ttl", http.StatusBadRequest)
+			return nil
+		}
+	}
+	token, expireAt, err := s.CreateShare(req.Context(), sess, page, page.Path, ttl, query.Get("ip"))
+	switch {
+	case errors.Is(err, errShareForbidden):
+		s.config.OnForbidden(w, req, err)
+		return nil
+	case errors.Is(err, errShareDisabled):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return nil
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(map[string]any{
+		"url":       ShareURL(req, target, token),
+		"expire_at": expireAt,
+	})
+}
+
+// ShareURL 返回本域名下携带分享令牌的页面地址
+func ShareURL(req *http.Request, target, token string) string {
+	return (&url.URL{
+		Scheme:   RequestInfoFromRequest(req).Scheme,
+		Host:     req.Host,
+		Path:     target,
+		RawQuery: url.Values{ShareQueryKey: {token}}.Encode(),
+	}).String()
+}
+
+// sharePrefix 规范化仓库内路径前缀，不含首尾 /，空字符串表示整个仓库
+func sharePrefix(prefix string) string {
+	return strings.Trim(path.Clean("/"+prefix), "/")
+}
+
+// shareCovers 判断路径是否位于分享前缀内，路径先行规范化避免 .. 越界
+func shareCovers(prefix, target string) bool {
+	target = sharePrefix(target)
+	return prefix == "" || target == prefix || strings.HasPrefix(target, prefix+"/")
+}
+
+func parseShareIP(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,279 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ssoTicketTTL 跨域登录票据有效期
+const ssoTicketTTL = time.Minute
+
+// ssoTicket 统一登录域名签发给其他域名的一次性登录票据
+type ssoTicket struct {
+	SessionID string    `json:"session_id"`
+	Host      string    `json:"host"`
+	ReturnTo  string    `json:"return_to"`
+	ExpireAt  time.Time `json:"expire_at"`
+	// Sealed Cookie 会话模式下加密的统一登录会话，KV 中的会话记录不含提供方数据
+	Sealed string `json:"sealed,omitempty"`
+}
+
+// SetHostPolicy 设置可接收跨域登录票据的页面判断，通常仅允许页面域名与已绑定别名下需要登录的页面
+func (s *AuthService) SetHostPolicy(policy func(ctx context.Context, host, path string) bool) {
+	s.hostPolicy = policy
+}
+
+// SSOEnabled 是否启用跨域登录
+func (s *AuthService) SSOEnabled() bool {
+	return s.config.SSOHost != "" && len(s.config.Secret) > 0
+}
+
+// needsSSO 判断域名是否需要通过统一登录域名签发票据登录
+func (s *AuthService) needsSSO(host string) bool {
+	return s.SSOEnabled() && host != s.config.SSOHost && !s.cookieCovers(host)
+}
+
+// cookieCovers 判断登录 Cookie 是否对域名生效
+func (s *AuthService) cookieCovers(host string) bool {
+	domain := strings.ToLower(strings.TrimPrefix(s.config.CookieDomain, "."))
+	if domain == "" {
+		return !s.SSOEnabled() || host == s.config.SSOHost
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// handleSSO 在统一登录域名签发票据，或在目标域名兑换票据并创建仅对该域名有效的会话
+func (s *AuthService) handleSSO(w http.ResponseWriter, req *http.Request) error {
+	if !s.SSOEnabled() {
+		http.NotFound(w, req)
+		return nil
+	}
+	query := req.URL.Query()
+	if ticket := query.Get("ticket"); ticket != "" {
+		return s.redeemTicket(w, req, ticket)
+	}
+	if requestHost(req) != s.config.SSOHost {
+		http.NotFound(w, req)
+		return nil
+	}
+	host := strings.ToLower(query.Get("host"))
+	returnTo := sanitizeReturnTo(query.Get("return_to"))
+	if !s.needsSSO(host) || !s.allowsHost(req.Context(), host, returnTo) {
+		s.config.OnForbidden(w, req, errors.New("sso host not allowed"))
+		return nil
+	}
+	sess, ok, err := s.loadSession(req.Context(), req)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 先在统一登录域名登录，完成后回到此处签发票据
+		http.Redirect(w, req, AuthPathLogin+"?return_to="+url.QueryEscape(req.URL.RequestURI()), http.StatusFound)
+		return nil
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	confirm := s.signConfirm(sess.ID, host, returnTo)
+	if req.Method != http.MethodPost {
+		// 签发票据前由用户确认登录的目标域名，避免其他页面静默取得登录态
+		return renderSSOConfirm(w, ssoConfirmForm{
+			Action:  req.URL.RequestURI(),
+			Host:    host,
+			Name:    sess.Identity.Name,
+			Confirm: confirm,
+		})
+	}
+	if !hmac.Equal([]byte(req.PostFormValue("confirm")), []byte(confirm)) {
+		s.config.OnForbidden(w, req, errors.New("sso confirmation mismatch"))
+		return nil
+	}
+	var sealed string
+	if s.sealer != nil {
+		if sealed, err = s.sealer.seal(sess); err != nil {
+			return err
+		}
+	}
+	ticketID := uuid.NewString()
+	if err = s.tickets.Store(req.Context(), ticketID, ssoTicket{
+		SessionID: sess.ID,
+		Host:      host,
+		ReturnTo:  returnTo,
+		ExpireAt:  time.Now().Add(ssoTicketTTL),
+		Sealed:    sealed,
+	}); err != nil {
+		return err
+	}
+	http.Redirect(w, req, s.ssoURL(req, host, url.Values{"ticket": {ticketID + "." + s.signTicket(ticketID, host)}}), http.StatusSeeOther)
+	return nil
+}
+
+// allowsHost 判断域名下返回的页面是否可接收跨域登录票据
+func (s *AuthService) allowsHost(ctx context.Context, host, returnTo string) bool {
+	if s.hostPolicy == nil {
+		return false
+	}
+	target, err := url.Parse(returnTo)
+	return err == nil && s.hostPolicy(ctx, host, target.Path)
+}
+
+func (s *AuthService) redeemTicket(w http.ResponseWriter, req *http.Request, raw string) error {
+	host := requestHost(req)
+	ticketID, signature, ok := strings.Cut(raw, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signTicket(ticketID, host))) {
+		s.config.OnUnauthorized(w, req, errors.New("invalid sso ticket"))
+		return nil
+	}
+	ticket, ok, err := s.tickets.Load(req.Context(), ticketID)
+	if err != nil {
+		return err
+	}
+	if !ok || ticket.Host != host || time.Now().After(ticket.ExpireAt) {
+		s.config.OnUnauthorized(w, req, errors.New("invalid or expired sso ticket"))
+		return nil
+	}
+	// 票据仅可使用一次
+	_ = s.tickets.Delete(req.Context(), ticketID)
+	parent, ok, err := s.sessions.Load(req.Context(), ticket.SessionID)
+	if err != nil {
+		return err
+	}
+	if !ok || time.Now().After(parent.ExpireAt) {
+		s.config.OnUnauthorized(w, req, errors.New("sso session expired"))
+		return nil
+	}
+	// KV 会话的派生会话不复制提供方数据，调用提供方时读取统一登录会话
+	sess := AuthSession{
+		ID:       uuid.NewString(),
+		Identity: parent.Identity,
+		ExpireAt: parent.ExpireAt,
+		Parent:   parent.ID,
+	}
+	if s.sealer != nil {
+		// Cookie 会话的提供方数据随票据加密传递，保存在派生会话自身的 Cookie 中
+		sealed, ok := s.sealer.open(ticket.Sealed)
+		if !ok || sealed.ID != parent.ID {
+			s.config.OnUnauthorized(w, req, errors.New("invalid sso ticket"))
+			return nil
+		}
+		sess.Identity = sealed.Identity
+		sess.Private = sealed.Private
+	}
+	// storeSession 同时将会话加入统一登录会话的派生会话索引
+	if err = s.storeSession(req.Context(), req, &sess); err != nil {
+		return err
+	}
+	if err = s.setSessionCookie(w, &sess, ""); err != nil {
+		return err
+	}
+	http.Redirect(w, req, ticket.ReturnTo, http.StatusFound)
+	return nil
+}
+
+// providerSession 返回调用提供方时使用的会话，跨域登录派生的会话使用其统一登录会话的提供方数据，
+// Cookie 会话的派生会话自身携带提供方数据；统一登录会话已失效时返回 false
+func (s *AuthService) providerSession(ctx context.Context, sess *AuthSession) (*AuthSession, bool, error) {
+	if sess.Parent == "" {
+		return sess, true, nil
+	}
+	parent, ok, err := s.loadSessionByID(ctx, sess.Parent)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if s.sealer != nil {
+		return sess, true, nil
+	}
+	result := *sess
+	result.Private = parent.Private
+	return &result, true, nil
+}
+
+// revokeSSO 撤销会话以及同一次统一登录派生的所有域名会话
+func (s *AuthService) revokeSSO(ctx context.Context, sess *AuthSession) error {
+	root := sess
+	if sess.Parent != "" {
+		// 统一登录会话可能已过期，仍按其 ID 清理派生的会话
+		root = &AuthSession{ID: sess.Parent, Identity: sess.Identity}
+		if parent, ok, err := s.loadSessionByID(ctx, sess.Parent); err == nil && ok {
+			root = parent
+		}
+	}
+	return s.RevokeSession(ctx, root)
+}
+
+// signTicket 票据签名绑定目标域名，防止票据被转交到其他域名使用
+func (s *AuthService) signTicket(ticketID, host string) string {
+	mac := hmac.New(sha256.New, s.config.Secret)
+	mac.Write([]byte("sso\x00" + ticketID + "\x00" + host))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signConfirm 确认签发票据的表单令牌，绑定统一登录会话、目标域名与返回地址
+func (s *AuthService) signConfirm(sessionID, host, returnTo string) string {
+	mac := hmac.New(sha256.New, s.config.Secret)
+	mac.Write([]byte("sso-confirm\x00" + sessionID + "\x00" + host + "\x00" + returnTo))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *AuthService) ssoURL(req *http.Request, host string, values url.Values) string {
+	scheme := RequestInfoFromRequest(req).Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	return scheme + "://" + host + AuthPathSSO + "?" + values.Encode()
+}
+
+// requestHost 返回小写且不含端口的请求域名
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if index := strings.LastIndexByte(host, ':'); index > 0 && !strings.HasSuffix(host, "]") {
+		host = host[:index]
+	}
+	return strings.ToLower(host)
+}
+
+type ssoConfirmForm struct {
+	Action  string
+	Host    string
+	Name    string
+	Confirm string
+}
+
+var ssoConfirmTemplate = template.Must(template.New("sso").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Sign in to {{.Host}}</title>
+<style>
+body{font-family:system-ui,sans-serif;display:flex;justify-content:center;padding-top:15vh;margin:0}
+form{display:flex;flex-direction:column;gap:.75rem;width:18rem}
+button{font:inherit;padding:.5rem}
+</style>
+</head>
+<body>
+<form method="post" action="{{.Action}}">
+<h1>Sign in to {{.Host}}</h1>
+<p>Continue as <strong>{{.Name}}</strong>?</p>
+<input type="hidden" name="confirm" value="{{.Confirm}}">
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+func renderSSOConfirm(w http.ResponseWriter, form ssoConfirmForm) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(http.StatusOK)
+	return ssoConfirmTemplate.Execute(w, form)
+}
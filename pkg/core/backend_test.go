@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+// memoryRepoBackend 内存仓库后端，供元数据相关测试共用
+type memoryRepoBackend struct {
+	mu        sync.Mutex
+	commits   map[string]string
+	files     map[string]string
+	metaCalls map[string]int
+}
+
+func newMemoryRepoBackend() *memoryRepoBackend {
+	return &memoryRepoBackend{
+		commits:   make(map[string]string),
+		files:     make(map[string]string),
+		metaCalls: make(map[string]int),
+	}
+}
+
+func (b *memoryRepoBackend) set(owner, repo, commit string, files map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.commits[owner+"/"+repo] = commit
+	for path, data := range files {
+		b.files[owner+"/"+repo+"/"+commit+"/"+path] = data
+	}
+}
+
+func (b *memoryRepoBackend) Close() error { return nil }
+
+func (b *memoryRepoBackend) Meta(_ context.Context, owner, repo string) (*Metadata, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.metaCalls[owner+"/"+repo]++
+	commit, ok := b.commits[owner+"/"+repo]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &Metadata{ID: commit, LastModified: time.Now()}, nil
+}
+
+func (b *memoryRepoBackend) Open(_ context.Context, owner, repo, commit, path string, _ http.Header) (*http.Response, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.files[owner+"/"+repo+"/"+commit+"/"+path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader([]byte(data))),
+	}, nil
+}
+
+func (b *memoryRepoBackend) List(context.Context, string, string, string, string) ([]DirEntry, error) {
+	return nil, os.ErrNotExist
+}
+
+// newTestServerMeta 以内存 KV 与给定后端创建 ServerMeta
+func newTestServerMeta(t *testing.T, backend Backend, filters map[string]FilterInstance) *ServerMeta {
+	t.Helper()
+	store, err := kv.NewMemory("")
+	require.NoError(t, err)
+	meta, err := NewServerMeta(http.DefaultClient, backend, "example.com", NewDomainAlias(store.Child("alias")),
+		store.Child("cache"), time.Hour, time.Hour, 1, filters, nil)
+	require.NoError(t, err)
+	return meta
+}
+
+// noopFilters 仅用于编译路由表的默认过滤器
+func noopFilters() map[string]FilterInstance {
+	noop := func(Params) (FilterCall, error) { return nil, nil }
+	return map[string]FilterInstance{"404": noop, "block": noop, "direct": noop}
+}
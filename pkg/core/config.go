@@ -8,21 +8,52 @@ import (
 )
 
 type PageConfig struct {
-	Alias    []string          `yaml:"alias"`    // 页面附加域名 / 别名
-	Routes   []PageConfigRoute `yaml:"routes"`   // 路由配置
-	Private  bool              `yaml:"private"`  // 是否私有
-	Security PageSecurity      `yaml:"security"` // 页面安全策略
+	Alias     []string          `yaml:"alias"`     // 页面附加域名 / 别名
+	Canonical string            `yaml:"canonical"` // 规范域名，自动绑定 apex/www 对应域名并 301 跳转至此
+	Routes    []PageConfigRoute `yaml:"routes"`    // 路由配置
+	Private   bool              `yaml:"private"`   // 是否私有
+	Access    *PageAccess       `yaml:"access"`    // 访问规则，声明后页面需要登录
+	Password  *PagePassword     `yaml:"password"`  // 访问口令，与登录相互独立
+	Hosting   string            `yaml:"hosting"`   // 托管模式 path / subdomain
+	Security  PageSecurity      `yaml:"security"`  // 页面安全策略
+
+	CleanURLs     bool   `yaml:"clean_urls"`     // 省略 .html 扩展名访问页面
+	TrailingSlash string `yaml:"trailing_slash"` // 结尾斜杠策略 auto / always / never
 }
 
+const (
+	HostingPath      = "path"      // <owner>.<domain>/<repo>/
+	HostingSubdomain = "subdomain" // <repo>.<owner>.<domain>/
+)
+
+const (
+	TrailingSlashAuto   = "auto"   // 目录以 / 结尾，文件保持原样
+	TrailingSlashAlways = "always" // 目录与省略扩展名的页面均以 / 结尾
+	TrailingSlashNever  = "never"  // 均不以 / 结尾 (页面根路径除外)
+)
+
 type PageConfigRoute struct {
 	Path   string         `yaml:"path"`   // 路由匹配模式
 	Type   string         `yaml:"type"`   // filter 名称
 	Params map[string]any `yaml:"params"` // filter 参数
+	Match  *RouteMatch    `yaml:"match"`  // 附加匹配条件
+	Line   int            `yaml:"-"`      // 路由在 .pages.yaml 中的行号
 }
 
 func (p *PageConfigRoute) UnmarshalYAML(value *yaml.Node) error {
+	if value == nil {
+		return errors.New("route must be a mapping")
+	}
+	p.Line = value.Line
+	if err := p.unmarshalYAML(value); err != nil {
+		return errors.Wrapf(err, "line %d", value.Line)
+	}
+	return nil
+}
+
+func (p *PageConfigRoute) unmarshalYAML(value *yaml.Node) error {
 	p.Params = make(map[string]any)
-	if value == nil || value.Kind != yaml.MappingNode {
+	if value.Kind != yaml.MappingNode {
 		return errors.New("route must be a mapping")
 	}
 
@@ -51,6 +82,16 @@ func (p *PageConfigRoute) UnmarshalYAML(value *yaml.Node) error {
 			pathFound = true
 			continue
 		}
+		if key == "match" {
+			if p.Match != nil {
+				return errors.New("duplicate match field")
+			}
+			p.Match = &RouteMatch{}
+			if err := node.Decode(p.Match); err != nil {
+				return errors.Wrap(err, "invalid route match")
+			}
+			continue
+		}
 		if p.Type != "" {
 			return errors.Errorf("route must define exactly one filter, got %q and %q", p.Type, key)
 		}
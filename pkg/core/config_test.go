@@ -123,3 +123,60 @@ routes:
 		})
 	}
 }
+
+func TestPageConfigRouteMatch(t *testing.T) {
+	var cfg PageConfig
+	require.NoError(t, yaml.Unmarshal([]byte(`
+routes:
+  - path: "api/**"
+    match:
+      methods: [post, put]
+      hosts: ["*.example.org"]
+      headers:
+        x-beta: "on"
+      query:
+        preview: ""
+    js:
+      exec: index.js
+`), &cfg))
+	require.Len(t, cfg.Routes, 1)
+	route := cfg.Routes[0]
+	assert.Equal(t, "js", route.Type)
+	require.NotNil(t, route.Match)
+	require.NoError(t, route.Match.Normalize())
+	assert.Equal(t, []string{"POST", "PUT"}, route.Match.Methods)
+	assert.Equal(t, map[string]string{"X-Beta": "on"}, route.Match.Headers)
+	assert.Equal(t, map[string]string{"preview": ""}, route.Match.Query)
+
+	for _, match := range []RouteMatch{
+		{Methods: []string{"GET POST"}},
+		{Hosts: []string{""}},
+		{Headers: map[string]string{"bad header": ""}},
+		{Query: map[string]string{"": ""}},
+		{Query: map[string]string{"a": "[unclosed"}},
+	} {
+		assert.Error(t, match.Normalize(), "%+v", match)
+	}
+}
+
+func TestPageConfigRouteErrorsIncludeLine(t *testing.T) {
+	var cfg PageConfig
+	err := yaml.Unmarshal([]byte(`
+routes:
+  - path: "api/**"
+    js: {}
+  - path: "docs/**"
+    js: {}
+    direct: {}
+`), &cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 5: route must define exactly one filter")
+
+	cfg = PageConfig{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+routes:
+  - path: "api/**"
+    js: {}
+`), &cfg))
+	assert.Equal(t, 3, cfg.Routes[0].Line)
+}
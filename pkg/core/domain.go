@@ -3,38 +3,87 @@ package core
 import (
 	"context"
 	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/golang-lru/v2/expirable"
 	"github.com/pkg/errors"
 )
 
+const (
+	// cnameCacheTTL CNAME 查询结果 (含未指向组织的结果) 的缓存时间
+	cnameCacheTTL  = 5 * time.Minute
+	cnameCacheSize = 4096
+	// cnameLookupTimeout 单次 CNAME 查询超时
+	cnameLookupTimeout = 2 * time.Second
+	// cnameConcurrent 同时进行的 CNAME 查询上限，超出时视为未绑定
+	cnameConcurrent = 16
+)
+
+var regexpHostLabels = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
 type PageDomain struct {
 	*ServerMeta
 
+	// RepoSubdomain 启用 <repo>.<owner>.<domain> 子域名模式，未命中时回退到路径模式
+	RepoSubdomain bool
+	// LookupCNAME 查询域名的 CNAME 记录，未绑定的域名据此定位组织并刷新组织域名绑定
+	LookupCNAME func(ctx context.Context, host string) (string, error)
+
 	baseDomain string
+
+	cnames       *expirable.LRU[string, string]
+	cnameMu      sync.Mutex
+	cnameLookups map[string]*cnameLookup
+	cnameSem     chan struct{}
+}
+
+type cnameLookup struct {
+	done  chan struct{}
+	owner string
 }
 
 func NewPageDomain(meta *ServerMeta, baseDomain string) *PageDomain {
 	return &PageDomain{
-		baseDomain: baseDomain,
-		ServerMeta: meta,
+		baseDomain:   baseDomain,
+		ServerMeta:   meta,
+		LookupCNAME:  net.DefaultResolver.LookupCNAME,
+		cnames:       expirable.NewLRU[string, string](cnameCacheSize, nil, cnameCacheTTL),
+		cnameLookups: make(map[string]*cnameLookup),
+		cnameSem:     make(chan struct{}, cnameConcurrent),
 	}
 }
 
 type PageContent struct {
 	*PageMetaContent
 
-	Owner string
-	Repo  string
-	Path  string
+	Owner     string
+	Repo      string
+	Path      string
+	Subdomain string // 通配符别名匹配到的子域名部分
+	BasePath  string // 页面在 URL 中的挂载前缀，路径模式下为 /<repo>
 }
 
 func (p *PageDomain) ParseDomainMeta(ctx context.Context, domain, path string) (*PageContent, error) {
 	pathArr := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if !isASCII(domain) {
+		normalized, err := NormalizeDomain(domain)
+		if err != nil {
+			return nil, errors.Wrap(os.ErrNotExist, err.Error())
+		}
+		domain = normalized
+	}
 	defaultRepo := domain
 	if !strings.HasSuffix(domain, "."+p.baseDomain) {
-		alias, err := p.Alias.Query(ctx, domain) // 确定 alias 是否存在内容
+		alias, subdomain, err := p.Alias.Match(ctx, domain) // 确定 alias 是否存在内容
+		if errors.Is(err, os.ErrNotExist) && p.resolveOrgDomain(ctx, domain) {
+			alias, subdomain, err = p.Alias.Match(ctx, domain)
+		}
 		if err != nil {
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 				return nil, err
@@ -45,19 +94,116 @@ func (p *PageDomain) ParseDomainMeta(ctx context.Context, domain, path string) (
 			slog.Warn("unknown domain", "base", p.baseDomain, "domain", domain, "error", err)
 			return nil, os.ErrNotExist
 		}
-		slog.Debug("alias hit", "domain", domain, "alias", alias)
-		return p.returnMeta(ctx, alias.Owner, alias.Repo, pathArr)
+		slog.Debug("alias hit", "domain", domain, "alias", alias, "subdomain", subdomain)
+		var result *PageContent
+		if alias.Repo == "" {
+			// 组织域名，路径选择仓库
+			result, err = p.parseOwnerPath(ctx, alias.Owner, defaultRepo, pathArr)
+		} else {
+			result, err = p.returnMeta(ctx, alias.Owner, alias.Repo, pathArr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Subdomain = subdomain
+		return result, nil
 	}
 	owner := strings.TrimSuffix(domain, "."+p.baseDomain)
+	if index := strings.LastIndexByte(owner, '.'); p.RepoSubdomain && index > 0 {
+		// 子域名模式: <repo>.<owner>.<domain>
+		result, err := p.returnMeta(ctx, owner[index+1:], owner[:index], pathArr)
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		slog.Debug("fail back to path mode", "domain", domain)
+	}
+	return p.parseOwnerPath(ctx, owner, defaultRepo, pathArr)
+}
+
+// resolveOrgDomain 未绑定的域名通过 CNAME 指向 <owner>.<domain> 时刷新该组织的配置，
+// 组织域名无需先经由基础域名访问即可绑定；返回是否刷新了组织配置
+func (p *PageDomain) resolveOrgDomain(ctx context.Context, domain string) bool {
+	if p.OrgRepo == "" || p.LookupCNAME == nil {
+		return false
+	}
+	owner := p.cnameOwner(ctx, domain)
+	if owner == "" {
+		return false
+	}
+	if _, err := p.GetOrgMeta(ctx, owner); err != nil {
+		slog.Warn("resolve org domain failed", "domain", domain, "owner", owner, "error", err)
+		return false
+	}
+	return true
+}
+
+// cnameOwner 返回域名 CNAME 指向的组织，结果按 cnameCacheTTL 缓存，
+// 同一域名的并发请求共用一次查询
+func (p *PageDomain) cnameOwner(ctx context.Context, domain string) string {
+	if owner, ok := p.cnames.Get(domain); ok {
+		return owner
+	}
+	p.cnameMu.Lock()
+	lookup, ok := p.cnameLookups[domain]
+	if !ok {
+		select {
+		case p.cnameSem <- struct{}{}:
+		default:
+			p.cnameMu.Unlock()
+			slog.Debug("too many concurrent cname lookups", "domain", domain)
+			return ""
+		}
+		lookup = &cnameLookup{done: make(chan struct{})}
+		p.cnameLookups[domain] = lookup
+		go p.runCNAMELookup(domain, lookup)
+	}
+	p.cnameMu.Unlock()
+	select {
+	case <-ctx.Done():
+		return ""
+	case <-lookup.done:
+		return lookup.owner
+	}
+}
+
+func (p *PageDomain) runCNAMELookup(domain string, lookup *cnameLookup) {
+	defer func() {
+		p.cnameMu.Lock()
+		delete(p.cnameLookups, domain)
+		p.cnameMu.Unlock()
+		<-p.cnameSem
+		close(lookup.done)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), cnameLookupTimeout)
+	defer cancel()
+	cname, err := p.LookupCNAME(ctx, domain)
+	if err == nil {
+		owner, ok := strings.CutSuffix(strings.ToLower(strings.TrimSuffix(cname, ".")), "."+p.baseDomain)
+		if ok && owner != "" {
+			// 子域名模式下 CNAME 可能指向 <repo>.<owner>.<domain>
+			lookup.owner = owner[strings.LastIndexByte(owner, '.')+1:]
+		}
+	}
+	p.cnames.Add(domain, lookup.owner)
+}
+
+// parseOwnerPath 路径模式：路径第一段为仓库，未命中时回退到默认仓库
+func (p *PageDomain) parseOwnerPath(ctx context.Context, owner, defaultRepo string, pathArr []string) (*PageContent, error) {
 	repo := pathArr[0]
 	var returnMeta *PageContent
 	var err error
 	if repo == "" {
 		// 回退到默认仓库 (路径未包含仓库)
-		slog.Debug("fail back to default repo", "repo", domain)
+		slog.Debug("fail back to default repo", "repo", defaultRepo)
 		returnMeta, err = p.returnMeta(ctx, owner, defaultRepo, pathArr)
 	} else {
 		returnMeta, err = p.returnMeta(ctx, owner, repo, pathArr[1:])
+		if err == nil {
+			returnMeta.BasePath = "/" + repo
+		}
 	}
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return nil, err
@@ -68,7 +214,37 @@ func (p *PageDomain) ParseDomainMeta(ctx context.Context, domain, path string) (
 	return p.returnMeta(ctx, owner, defaultRepo, pathArr)
 }
 
+// SubdomainRedirect 页面声明 hosting: subdomain 但通过路径模式访问时，返回子域名模式下的地址
+func (p *PageDomain) SubdomainRedirect(meta *PageContent, requestURL *url.URL, scheme string) (string, bool) {
+	if !p.RepoSubdomain || meta.Hosting != HostingSubdomain || meta.BasePath == "" {
+		return "", false
+	}
+	if len(redirectTargets(meta.Alias)) > 0 {
+		// 已绑定别名的页面由 redirect 处理
+		return "", false
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+	prefix := strings.ToLower(meta.Repo + "." + meta.Owner)
+	if !regexpHostLabels.MatchString(prefix) {
+		// 仓库名无法作为域名时保持路径模式
+		return "", false
+	}
+	target := &url.URL{
+		Scheme:   scheme,
+		Host:     prefix + "." + p.baseDomain,
+		Path:     "/" + meta.Path,
+		RawQuery: requestURL.RawQuery,
+	}
+	return target.String(), true
+}
+
 func (p *PageDomain) returnMeta(ctx context.Context, owner, repo string, path []string) (*PageContent, error) {
+	if repo == p.OrgRepo {
+		// 组织级配置仓库不作为页面提供
+		return nil, errors.Wrap(os.ErrNotExist, strings.Join(path, "/"))
+	}
 	result := &PageContent{}
 	meta, err := p.GetMeta(ctx, owner, repo)
 	if err != nil {
@@ -88,3 +264,12 @@ func (p *PageDomain) returnMeta(ctx context.Context, owner, repo string, path []
 
 	return result, nil
 }
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
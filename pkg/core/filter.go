@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"gopkg.d7z.net/middleware/kv"
 	"gopkg.d7z.net/middleware/storage"
@@ -19,15 +20,26 @@ type FilterContext struct {
 	context.Context
 	*PageContent
 	*PageVFS
-	Cache        *tools.TTLCache
-	OrgDB        kv.KV
-	RepoDB       kv.KV
-	Storage      storage.Storage
-	VersionEvent subscribe.Subscriber
-	SharedEvent  subscribe.Subscriber
-	Auth         AuthInfo
+	Cache           *tools.TTLCache
+	OrgDB           kv.KV
+	RepoDB          kv.KV
+	Storage         storage.Storage
+	VersionEvent    subscribe.Subscriber
+	SharedEvent     subscribe.Subscriber
+	Auth            AuthInfo
+	RouteParams     RouteParams // 当前过滤器路由捕获的参数
+	ResponseHeaders http.Header // 写入响应头时覆盖的自定义响应头
+	Protected       bool        // 页面或路由要求访问口令，响应缓存标记为 private
 
 	Kill func()
+	// Authorize 要求请求已登录并满足访问规则，未通过时已写入响应
+	Authorize func(writer http.ResponseWriter, request *http.Request, access *PageAccess) (bool, error)
+	// RequirePassword 要求请求携带有效的口令 Cookie，未通过时已写入响应
+	RequirePassword func(writer http.ResponseWriter, request *http.Request, password *PagePassword) bool
+	// Share 以当前登录用户身份为仓库内路径签发分享链接，返回链接与过期时间
+	Share func(path string, ttl time.Duration, ip string) (string, time.Time, error)
+	// Rewrite 以仓库内的新路径重新进入过滤器链，请求地址不变
+	Rewrite func(ctx FilterContext, writer http.ResponseWriter, request *http.Request, path string) error
 }
 
 type Params map[string]any
@@ -46,13 +58,15 @@ func (f Params) Unmarshal(target any) error {
 }
 
 type Filter struct {
-	Path   string `json:"path"`
-	Type   string `json:"type"`
-	Params Params `json:"params"`
+	Path   string      `json:"path"`
+	Type   string      `json:"type"`
+	Params Params      `json:"params"`
+	Match  *RouteMatch `json:"match,omitempty"`
 }
 
-func NextCallWrapper(call FilterCall, parentCall NextCall, stack Filter) NextCall {
+func NextCallWrapper(call FilterCall, parentCall NextCall, stack Filter, params RouteParams) NextCall {
 	return func(ctx FilterContext, writer http.ResponseWriter, request *http.Request) error {
+		ctx.RouteParams = params
 		slog.Debug(fmt.Sprintf("call filter(%s) before", stack.Type), "filter", stack)
 		err := call(ctx, writer, request, parentCall)
 		slog.Debug(fmt.Sprintf("call filter(%s) after", stack.Type), "filter", stack, "error", err)
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HeadersFile 仓库根目录下 Netlify 风格的响应头规则文件
+const HeadersFile = "_headers"
+
+// restrictedResponseHeaders 由服务端维护，不允许通过规则覆盖
+var restrictedResponseHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Keep-Alive":        true,
+	"Set-Cookie":        true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// HeaderRule _headers 中的一组规则
+type HeaderRule struct {
+	Line    int
+	Path    string // 路由模式
+	Headers http.Header
+}
+
+// ValidateResponseHeader 校验自定义响应头名称与值
+func ValidateResponseHeader(name, value string) error {
+	if !regexpToken.MatchString(name) {
+		return errors.Errorf("invalid header name %q", name)
+	}
+	if restrictedResponseHeaders[http.CanonicalHeaderKey(name)] {
+		return errors.Errorf("header %q cannot be customized", name)
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return errors.Errorf("invalid value for header %q", name)
+	}
+	return nil
+}
+
+// ParseHeaders 解析 _headers 文件
+//
+// 顶格的行为路径，其后缩进的 `名称: 值` 行为该路径的响应头，同名响应头可重复声明。
+func ParseHeaders(data string) ([]HeaderRule, error) {
+	result := make([]HeaderRule, 0)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if raw[0] != ' ' && raw[0] != '\t' {
+			if !strings.HasPrefix(text, "/") {
+				return nil, errors.Errorf("%s line %d: invalid path %q", HeadersFile, line, text)
+			}
+			path := headerRoutePattern(text)
+			if _, err := CompileRoutePattern(path); err != nil {
+				return nil, errors.Wrapf(err, "%s line %d", HeadersFile, line)
+			}
+			result = append(result, HeaderRule{Line: line, Path: path, Headers: make(http.Header)})
+			continue
+		}
+		if len(result) == 0 {
+			return nil, errors.Errorf("%s line %d: header without path", HeadersFile, line)
+		}
+		name, value, ok := strings.Cut(text, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok {
+			return nil, errors.Errorf("%s line %d: invalid header %q", HeadersFile, line, text)
+		}
+		if err := ValidateResponseHeader(name, value); err != nil {
+			return nil, errors.Wrapf(err, "%s line %d", HeadersFile, line)
+		}
+		result[len(result)-1].Headers.Add(name, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "read %s failed", HeadersFile)
+	}
+	return result, nil
+}
+
+// headerRoutePattern 将 _headers 路径转换为路由模式，不以通配符结尾的路径同时匹配目录首页
+func headerRoutePattern(path string) string {
+	path = escapeNetlifyPath(strings.Trim(path, "/"))
+	switch {
+	case path == "":
+		return "index.html"
+	case strings.HasSuffix(path, "*"):
+		return path
+	default:
+		return path + "{,/index.html}"
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeaders(t *testing.T) {
+	rules, err := ParseHeaders(`
+# 全站
+/*
+  X-Frame-Options: DENY
+  Link: </style.css>; rel=preload; as=style
+  Link: </app.js>; rel=preload; as=script
+
+/docs/
+	Cache-Control: no-cache
+/
+  X-Home: 1
+`)
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	assert.Equal(t, "*", rules[0].Path)
+	assert.Equal(t, 3, rules[0].Line)
+	assert.Equal(t, []string{"</style.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"}, rules[0].Headers.Values("Link"))
+	assert.Equal(t, "docs{,/index.html}", rules[1].Path)
+	assert.Equal(t, "no-cache", rules[1].Headers.Get("Cache-Control"))
+	assert.Equal(t, "index.html", rules[2].Path)
+}
+
+func TestParseHeadersErrors(t *testing.T) {
+	tests := map[string]string{
+		"  X-A: 1":              "header without path",
+		"docs":                  "invalid path",
+		"/a\n  X-A":             "invalid header",
+		"/a\n  Set-Cookie: a=b": "cannot be customized",
+		"/a\n  Bad Name: 1":     "invalid header name",
+	}
+	for data, expected := range tests {
+		_, err := ParseHeaders(data)
+		require.Error(t, err, data)
+		assert.Contains(t, err.Error(), "_headers line", data)
+		assert.Contains(t, err.Error(), expected, data)
+	}
+}
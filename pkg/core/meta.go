@@ -2,6 +2,8 @@ package core
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -9,11 +11,13 @@ import (
 	"os"
 	"regexp"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/gobwas/glob"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/net/publicsuffix"
 	"gopkg.d7z.net/middleware/kv"
 	"gopkg.d7z.net/middleware/tools"
 	"gopkg.in/yaml.v3"
@@ -21,19 +25,25 @@ import (
 	"github.com/pkg/errors"
 )
 
+const routeTableCacheSize = 1024
+
 type ServerMeta struct {
 	Backend
-	Domain string
-	Alias  *DomainAlias
-
-	client         *http.Client
-	cache          *tools.KVCache[PageMetaContent]
-	refresh        time.Duration
-	refreshSem     chan struct{}
-	enabledFilters map[string]struct{}
-	updatesMu      sync.Mutex
-	updates        map[string]*metaUpdate
-	updateHub      *RepoUpdateHub
+	Domain  string
+	Alias   *DomainAlias
+	OrgRepo string // 组织级配置仓库名称，为空时禁用
+
+	client      *http.Client
+	cache       *tools.KVCache[PageMetaContent]
+	orgCache    *tools.KVCache[OrgMetaContent]
+	refresh     time.Duration
+	refreshSem  chan struct{}
+	filters     map[string]FilterInstance
+	routeTables *lru.Cache[string, *RouteTable]
+	updatesMu   sync.Mutex
+	updates     map[string]*metaUpdate
+	orgUpdates  map[string]*orgUpdate
+	updateHub   *RepoUpdateHub
 }
 
 type metaUpdate struct {
@@ -42,19 +52,32 @@ type metaUpdate struct {
 	err  error
 }
 
+type orgUpdate struct {
+	done chan struct{}
+	meta *OrgMetaContent
+	err  error
+}
+
 // PageConfig 配置
 
 type PageMetaContent struct {
-	CommitID     string    `json:"commit_id"`     // 提交 COMMIT ID
-	LastModified time.Time `json:"last_modified"` // 上次更新时间
-	IsPage       bool      `json:"is_page"`       // 是否为 Page
-	Private      bool      `json:"private"`       // 是否私有页面
-	ErrorMsg     string    `json:"error"`         // 错误消息 (作为 500 错误日志暴露至前端)
-	RefreshAt    time.Time `json:"refresh_at"`    // 下次刷新时间
-
-	Alias    []string     `json:"alias"`    // alias
-	Filters  []Filter     `json:"filters"`  // 路由消息
-	Security PageSecurity `json:"security"` // 页面安全策略
+	CommitID      string    `json:"commit_id"`      // 提交 COMMIT ID
+	LastModified  time.Time `json:"last_modified"`  // 上次更新时间
+	IsPage        bool      `json:"is_page"`        // 是否为 Page
+	Private       bool      `json:"private"`        // 是否私有页面
+	Hosting       string    `json:"hosting"`        // 托管模式
+	CleanURLs     bool      `json:"clean_urls"`     // 省略 .html 扩展名访问页面
+	TrailingSlash string    `json:"trailing_slash"` // 结尾斜杠策略
+	ErrorMsg      string    `json:"error"`          // 配置错误消息，仅向有写权限的用户展示
+	OrgCommitID   string    `json:"org_commit_id"`  // 合并的组织级配置 COMMIT ID
+	RouteDigest   string    `json:"route_digest"`   // 路由规则摘要，与提交共同确定编译后的路由表
+	RefreshAt     time.Time `json:"refresh_at"`     // 下次刷新时间
+
+	Alias    []string      `json:"alias"`              // alias
+	Filters  []Filter      `json:"filters"`            // 路由消息
+	Security PageSecurity  `json:"security"`           // 页面安全策略
+	Access   *PageAccess   `json:"access,omitempty"`   // 访问规则
+	Password *PagePassword `json:"password,omitempty"` // 访问口令
 }
 
 func NewEmptyPageMetaContent() *PageMetaContent {
@@ -82,6 +105,13 @@ func NewEmptyPageMetaContent() *PageMetaContent {
 	}
 }
 
+// RequiresLogin 页面是否需要登录，私有页面或声明了 auth 路由
+func (m *PageMetaContent) RequiresLogin() bool {
+	return m.Private || slices.ContainsFunc(m.Filters, func(filter Filter) bool {
+		return filter.Type == "auth"
+	})
+}
+
 func (m *PageMetaContent) String() string {
 	marshal, _ := json.Marshal(m)
 	return string(marshal)
@@ -96,42 +126,70 @@ func NewServerMeta(
 	ttl time.Duration,
 	refresh time.Duration,
 	refreshConcurrent int,
-	enabledFilters []string,
+	filters map[string]FilterInstance,
 	updateHub *RepoUpdateHub,
-) *ServerMeta {
+) (*ServerMeta, error) {
 	if refreshConcurrent <= 0 {
 		refreshConcurrent = 16
 	}
+	routeTables, err := lru.New[string, *RouteTable](routeTableCacheSize)
+	if err != nil {
+		return nil, err
+	}
 	return &ServerMeta{
-		Backend:        backend,
-		Domain:         domain,
-		Alias:          alias,
-		client:         client,
-		cache:          tools.NewCache[PageMetaContent](cache, "meta", ttl),
-		refresh:        refresh,
-		refreshSem:     make(chan struct{}, refreshConcurrent),
-		enabledFilters: toNameSet(enabledFilters),
-		updates:        make(map[string]*metaUpdate),
-		updateHub:      updateHub,
+		Backend:     backend,
+		Domain:      domain,
+		Alias:       alias,
+		client:      client,
+		cache:       tools.NewCache[PageMetaContent](cache, "meta", ttl),
+		orgCache:    tools.NewCache[OrgMetaContent](cache, "org", ttl),
+		OrgRepo:     DefaultOrgConfigRepo,
+		refresh:     refresh,
+		refreshSem:  make(chan struct{}, refreshConcurrent),
+		filters:     filters,
+		routeTables: routeTables,
+		updates:     make(map[string]*metaUpdate),
+		orgUpdates:  make(map[string]*orgUpdate),
+		updateHub:   updateHub,
+	}, nil
+}
+
+// RouteTable 获取页面当前提交的路由表，首次访问时编译并缓存
+func (s *ServerMeta) RouteTable(owner, repo string, meta *PageMetaContent) (*RouteTable, error) {
+	key := fmt.Sprintf("%s/%s@%s#%s#%s", owner, repo, meta.CommitID, meta.OrgCommitID, meta.RouteDigest)
+	if table, ok := s.routeTables.Get(key); ok {
+		return table, nil
+	}
+	table, err := NewRouteTable(meta.Filters, s.filters)
+	if err != nil {
+		return nil, err
 	}
+	s.routeTables.Add(key, table)
+	return table, nil
 }
 
-func toNameSet(items []string) map[string]struct{} {
-	result := make(map[string]struct{}, len(items))
-	for _, item := range items {
-		item = strings.TrimSpace(item)
-		if item == "" {
-			continue
-		}
-		result[item] = struct{}{}
+// isPageRepo 存在 index.html 或 .pages.yaml 任一即可视为 page 仓库；
+// clean_urls 只能在 .pages.yaml 中开启，仅有 <page>.html 的站点因此同样可以识别。
+// direct 仅以 index.html 作为目录索引，index.htm 不作为页面依据
+func isPageRepo(ctx context.Context, vfs *PageVFS) bool {
+	if ok, _ := vfs.Exists(ctx, "index.html"); ok {
+		return true
 	}
-	return result
+	ok, _ := vfs.Exists(ctx, ".pages.yaml")
+	return ok
+}
+
+// routeDigest 路由规则摘要，后端复用提交 ID 时 (如分支名) 规则变化仍会重新编译
+func routeDigest(filters []Filter) string {
+	data, _ := json.Marshal(filters)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
 }
 
 func (s *ServerMeta) GetMeta(ctx context.Context, owner, repo string) (*PageMetaContent, error) {
 	key := fmt.Sprintf("%s/%s", owner, repo)
 	if cache, found, _ := s.cache.Load(ctx, key); found {
-		if time.Now().After(cache.RefreshAt) {
+		if !s.isMetaFresh(ctx, owner, repo, &cache) {
 			if s.refresh == 0 {
 				return s.waitForMetaUpdate(ctx, owner, repo)
 			}
@@ -140,11 +198,27 @@ func (s *ServerMeta) GetMeta(ctx context.Context, owner, repo string) (*PageMeta
 		if cache.IsPage {
 			return &cache, nil
 		}
+		if cache.ErrorMsg != "" {
+			return nil, newPageConfigError(owner, repo, &cache)
+		}
 		return nil, os.ErrNotExist
 	}
 	return s.waitForMetaUpdate(ctx, owner, repo)
 }
 
+// isMetaFresh 判断缓存是否仍然有效，组织级配置仓库提交变化时同样视为过期
+func (s *ServerMeta) isMetaFresh(ctx context.Context, owner, repo string, cache *PageMetaContent) bool {
+	if time.Now().After(cache.RefreshAt) {
+		return false
+	}
+	if s.OrgRepo == "" || repo == s.OrgRepo || !cache.IsPage {
+		return true
+	}
+	// 仅读取组织配置缓存，过期时后台刷新，不阻塞请求
+	org, ok := s.cachedOrgMeta(ctx, owner)
+	return !ok || org.CommitID == cache.OrgCommitID
+}
+
 func (s *ServerMeta) waitForMetaUpdate(ctx context.Context, owner, repo string) (*PageMetaContent, error) {
 	update := s.getOrStartMetaUpdate(owner, repo)
 	select {
@@ -220,13 +294,25 @@ func (s *ServerMeta) runMetaUpdate(owner, repo string, update *metaUpdate) {
 func (s *ServerMeta) refreshMeta(ctx context.Context, owner, repo string) (*PageMetaContent, error) {
 	key := fmt.Sprintf("%s/%s", owner, repo)
 	// 再次检查缓存
-	if cache, found, _ := s.cache.Load(ctx, key); found && time.Now().Before(cache.RefreshAt) {
+	if cache, found, _ := s.cache.Load(ctx, key); found && s.isMetaFresh(ctx, owner, repo, &cache) {
 		if cache.IsPage {
 			return &cache, nil
 		}
+		if cache.ErrorMsg != "" {
+			return nil, newPageConfigError(owner, repo, &cache)
+		}
 		return nil, os.ErrNotExist
 	}
 
+	// 同步刷新组织级配置 (组织域名绑定与默认配置)
+	var org *OrgMetaContent
+	if repo != s.OrgRepo {
+		var err error
+		if org, err = s.GetOrgMeta(ctx, owner); err != nil {
+			slog.Warn("load org meta failed", "owner", owner, "error", err)
+		}
+	}
+
 	rel := NewEmptyPageMetaContent()
 	info, err := s.Meta(ctx, owner, repo)
 	if err != nil {
@@ -242,28 +328,29 @@ func (s *ServerMeta) refreshMeta(ctx context.Context, owner, repo string) (*Page
 	rel.LastModified = info.LastModified
 	rel.RefreshAt = time.Now().Add(s.refresh)
 
-	// 存在 index.html 或 .pages.yaml 任一即可视为 page 仓库
-	hasIndex, _ := vfs.Exists(ctx, "index.html")
-	hasConfig, _ := vfs.Exists(ctx, ".pages.yaml")
-	if !hasIndex && !hasConfig {
+	if !isPageRepo(ctx, vfs) {
 		rel.IsPage = false
 		_ = s.cache.Store(ctx, key, *rel)
 		return nil, os.ErrNotExist
 	}
 	rel.IsPage = true
 	// 解析配置
-	if err := s.parsePageConfig(ctx, rel, vfs); err != nil {
+	if err := s.parsePageConfig(ctx, rel, vfs, org); err != nil {
 		rel.IsPage = false
 		rel.ErrorMsg = err.Error()
 		_ = s.cache.Store(ctx, key, *rel)
-		return nil, err
+		return nil, newPageConfigError(owner, repo, rel)
 	}
+	rel.RouteDigest = routeDigest(rel.Filters)
 	// todo: 优化保存逻辑 ，减少写入
 	if err = s.Alias.Bind(ctx, rel.Alias, owner, repo); err != nil {
 		slog.Warn("alias binding error", "error", err)
 		return nil, err
 	}
 	_ = s.cache.Store(ctx, key, *rel)
+	if _, err = s.RouteTable(owner, repo, rel); err != nil {
+		slog.Warn("compile route table failed", "owner", owner, "repo", repo, "error", err)
+	}
 	if s.updateHub != nil {
 		if err = s.updateHub.PublishUpdate(ctx, owner, repo, rel.CommitID); err != nil {
 			slog.Warn("publish update event failed", "owner", owner, "repo", repo, "commit", rel.CommitID, "error", err)
@@ -272,7 +359,9 @@ func (s *ServerMeta) refreshMeta(ctx context.Context, owner, repo string) (*Page
 	return rel, nil
 }
 
-func (s *ServerMeta) parsePageConfig(ctx context.Context, meta *PageMetaContent, vfs *PageVFS) error {
+// parsePageConfig 解析页面配置，组织级默认配置优先级低于仓库 .pages.yaml：
+// 仓库中显式声明的字段覆盖组织默认值 (列表整体替换)，组织声明的 block 路径始终生效
+func (s *ServerMeta) parsePageConfig(ctx context.Context, meta *PageMetaContent, vfs *PageVFS, org *OrgMetaContent) error {
 	defer func() {
 		meta.Filters = append(meta.Filters, Filter{
 			Path: "**",
@@ -282,6 +371,21 @@ func (s *ServerMeta) parsePageConfig(ctx context.Context, meta *PageMetaContent,
 			},
 		})
 	}()
+	cfg := &PageConfig{
+		Security: DefaultPageSecurity(),
+	}
+	if org != nil && org.Exists {
+		meta.OrgCommitID = org.CommitID
+		cfg.Private = org.Defaults.Private
+		cfg.Security = org.Defaults.Security
+		for _, item := range org.Defaults.Block {
+			meta.Filters = append(meta.Filters, Filter{
+				Path:   item,
+				Type:   "block",
+				Params: map[string]any{},
+			})
+		}
+	}
 	alias := make([]string, 0)
 	cname, err := vfs.ReadString(ctx, "CNAME")
 	if cname != "" && err == nil {
@@ -293,21 +397,7 @@ func (s *ServerMeta) parsePageConfig(ctx context.Context, meta *PageMetaContent,
 	data, err := vfs.ReadString(ctx, ".pages.yaml")
 	if err != nil {
 		slog.Debug("failed to read meta data", "error", err.Error())
-		if len(alias) > 0 {
-			meta.Filters = append(meta.Filters, Filter{
-				Path: "**",
-				Type: "redirect",
-				Params: map[string]any{
-					"targets": alias,
-				},
-			})
-			meta.Alias = alias
-		}
-		return nil // 配置文件不存在不是错误
-	}
-
-	cfg := &PageConfig{
-		Security: DefaultPageSecurity(),
+		data = "" // 配置文件不存在不是错误
 	}
 	if err = yaml.Unmarshal([]byte(data), cfg); err != nil {
 		return errors.Wrap(err, "parse .pages.yaml failed")
@@ -325,51 +415,227 @@ func (s *ServerMeta) parsePageConfig(ctx context.Context, meta *PageMetaContent,
 			return fmt.Errorf("invalid alias %s", item)
 		}
 	}
-	if len(alias) > 0 {
+	// 处理规范域名
+	if cfg.Canonical != "" {
+		canonical, ok := s.AliasCheck(cfg.Canonical)
+		if !ok || IsWildcardDomain(canonical) {
+			return fmt.Errorf("invalid canonical domain %s", cfg.Canonical)
+		}
+		secondary := canonicalCounterpart(canonical)
+		if _, ok = s.AliasCheck(secondary); !ok {
+			return fmt.Errorf("invalid canonical domain %s", cfg.Canonical)
+		}
+		alias = slices.DeleteFunc(alias, func(item string) bool {
+			return item == canonical || item == secondary
+		})
+		alias = append([]string{canonical, secondary}, alias...)
 		meta.Filters = append(meta.Filters, Filter{
 			Path: "**",
 			Type: "redirect",
 			Params: map[string]any{
-				"targets": alias,
+				"targets":   []string{canonical},
+				"code":      http.StatusMovedPermanently,
+				"canonical": true,
+			},
+		})
+	} else if targets := redirectTargets(alias); len(targets) > 0 {
+		meta.Filters = append(meta.Filters, Filter{
+			Path: "**",
+			Type: "redirect",
+			Params: map[string]any{
+				"targets": targets,
 			},
 		})
 	}
 	meta.Alias = alias
 	meta.Private = cfg.Private
+	if cfg.Access != nil {
+		if err = cfg.Access.Normalize(); err != nil {
+			return err
+		}
+		meta.Access = cfg.Access
+		meta.Private = true
+	}
+	if cfg.Password != nil {
+		if err = cfg.Password.Normalize(); err != nil {
+			return err
+		}
+		meta.Password = cfg.Password
+	}
+	switch cfg.Hosting {
+	case "", HostingPath, HostingSubdomain:
+		meta.Hosting = cfg.Hosting
+	default:
+		return fmt.Errorf("invalid hosting mode %q", cfg.Hosting)
+	}
+	switch cfg.TrailingSlash {
+	case "", TrailingSlashAuto, TrailingSlashAlways, TrailingSlashNever:
+		meta.TrailingSlash = cfg.TrailingSlash
+	default:
+		return fmt.Errorf("invalid trailing_slash %q", cfg.TrailingSlash)
+	}
+	meta.CleanURLs = cfg.CleanURLs
 	meta.Security = cfg.Security
+	if err = s.appendHeaders(ctx, meta, vfs); err != nil {
+		return err
+	}
 	// 处理自定义路由
 	for _, r := range cfg.Routes {
-		for _, item := range strings.Split(r.Path, ",") {
-			item = strings.TrimSpace(item)
-			if item == "" {
-				continue
-			}
-			if _, err := glob.Compile(item); err != nil {
-				return errors.Wrapf(err, "invalid route glob pattern: %s", item)
-			}
-			if _, ok := s.enabledFilters[r.Type]; !ok {
-				return fmt.Errorf("unavailable filter %q in route %q", r.Type, item)
-			}
-			meta.Filters = append(meta.Filters, Filter{
-				Path:   item,
-				Type:   r.Type,
-				Params: r.Params,
-			})
+		if err = s.appendRouteFilters(meta, r); err != nil {
+			return errors.Wrapf(err, "line %d", r.Line)
+		}
+	}
+	if err = s.checkPasswordFiles(ctx, meta, vfs); err != nil {
+		return err
+	}
+	return s.appendRedirects(ctx, meta, vfs)
+}
+
+// checkPasswordFiles 校验页面与路由口令引用的 htpasswd 文件，并禁止直接访问这些文件；
+// 禁止访问的路由位于所有路由之前且直接返回 404，不会交由其他路由或改写读取文件
+func (s *ServerMeta) checkPasswordFiles(ctx context.Context, meta *PageMetaContent, vfs *PageVFS) error {
+	blocked := make(map[string]bool)
+	var denies []Filter
+	for _, password := range meta.passwords() {
+		if password.Htpasswd == "" || blocked[password.Htpasswd] {
+			continue
+		}
+		if _, err := password.loadHtpasswd(ctx, vfs); err != nil {
+			return err
+		}
+		if _, err := CompileRoutePattern(password.Htpasswd); err != nil {
+			return errors.Wrapf(err, "invalid htpasswd path %q", password.Htpasswd)
 		}
+		blocked[password.Htpasswd] = true
+		denies = append(denies, Filter{
+			Path:   password.Htpasswd,
+			Type:   "block",
+			Params: map[string]any{"code": http.StatusNotFound},
+		})
 	}
+	meta.Filters = append(denies, meta.Filters...)
 	return nil
 }
 
-var regexpHostname = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,18}$`)
+// appendHeaders 读取 _headers 文件，每组规则转换为 headers 路由，优先级低于 .pages.yaml 中的路由
+func (s *ServerMeta) appendHeaders(ctx context.Context, meta *PageMetaContent, vfs *PageVFS) error {
+	data, err := vfs.ReadString(ctx, HeadersFile)
+	if err != nil {
+		return nil // 文件不存在不是错误
+	}
+	rules, err := ParseHeaders(data)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	if _, ok := s.filters["headers"]; !ok {
+		return fmt.Errorf("unavailable filter %q required by %s", "headers", HeadersFile)
+	}
+	for _, rule := range rules {
+		params := make(Params, len(rule.Headers))
+		for name, values := range rule.Headers {
+			params[name] = values
+		}
+		meta.Filters = append(meta.Filters, Filter{
+			Path:   rule.Path,
+			Type:   "headers",
+			Params: params,
+		})
+	}
+	return nil
+}
 
+// appendRedirects 读取 _redirects 文件，全部规则编译为一个过滤器置于 direct 之前
+func (s *ServerMeta) appendRedirects(ctx context.Context, meta *PageMetaContent, vfs *PageVFS) error {
+	data, err := vfs.ReadString(ctx, RedirectsFile)
+	if err != nil {
+		return nil // 文件不存在不是错误
+	}
+	rules, err := ParseRedirects(data)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	instance, ok := s.filters["redirects"]
+	if !ok {
+		return fmt.Errorf("unavailable filter %q required by %s", "redirects", RedirectsFile)
+	}
+	params := Params{"rules": rules}
+	if _, err = instance(params); err != nil {
+		return err
+	}
+	meta.Filters = append(meta.Filters, Filter{
+		Path:   "**",
+		Type:   "redirects",
+		Params: params,
+	})
+	return nil
+}
+
+func (s *ServerMeta) appendRouteFilters(meta *PageMetaContent, r PageConfigRoute) error {
+	if err := r.Match.Normalize(); err != nil {
+		return errors.Wrapf(err, "invalid match in route %q", r.Path)
+	}
+	for _, item := range strings.Split(r.Path, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if _, err := CompileRoutePattern(item); err != nil {
+			return errors.Wrapf(err, "invalid route pattern: %s", item)
+		}
+		instance, ok := s.filters[r.Type]
+		if !ok {
+			return fmt.Errorf("unavailable filter %q in route %q", r.Type, item)
+		}
+		// 提前实例化，参数错误在刷新时即可暴露
+		if _, err := instance(r.Params); err != nil {
+			return errors.Wrapf(err, "invalid %s params in route %q", r.Type, item)
+		}
+		meta.Filters = append(meta.Filters, Filter{
+			Path:   item,
+			Type:   r.Type,
+			Params: r.Params,
+			Match:  r.Match,
+		})
+	}
+	return nil
+}
+
+var regexpHostname = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+([a-z]{2,18}|xn--[a-z0-9-]{1,59})$`)
+
+// AliasCheck 校验并规范化别名，国际化域名统一转换为 punycode，支持 *.example.com 形式的通配符别名
 func (s *ServerMeta) AliasCheck(cname string) (string, bool) {
-	cname = strings.TrimSpace(cname)
-	if !regexpHostname.MatchString(cname) {
+	cname, err := NormalizeDomain(cname)
+	if err != nil || !regexpHostname.MatchString(cname) {
 		return "", false
 	}
+	// 通配符别名需位于可注册域名之下，避免占用 *.co.uk、*.github.io 等公共后缀下的全部域名
+	if base, ok := strings.CutPrefix(cname, "*."); ok {
+		if _, err = publicsuffix.EffectiveTLDPlusOne(base); err != nil {
+			return "", false
+		}
+	}
 
-	if strings.HasSuffix(strings.ToLower(cname), strings.ToLower(s.Domain)) {
+	if strings.HasSuffix(cname, strings.ToLower(s.Domain)) {
 		return "", false
 	}
 	return cname, true
 }
+
+// canonicalCounterpart 返回规范域名对应的 apex / www 域名
+func canonicalCounterpart(domain string) string {
+	if apex, ok := strings.CutPrefix(domain, "www."); ok {
+		return apex
+	}
+	return "www." + domain
+}
+
+// redirectTargets 返回可作为跳转目标的别名，通配符别名无法作为目标
+func redirectTargets(alias []string) []string {
+	targets := make([]string, 0, len(alias))
+	for _, item := range alias {
+		if !IsWildcardDomain(item) {
+			targets = append(targets, item)
+		}
+	}
+	return targets
+}
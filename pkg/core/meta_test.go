@@ -3,9 +3,11 @@ package core
 import (
 	"context"
 	"net/http"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.d7z.net/middleware/kv"
@@ -31,7 +33,7 @@ func TestRunMetaUpdateRecoversFromPanic(t *testing.T) {
 	store, err := kv.NewMemory("")
 	require.NoError(t, err)
 
-	meta := NewServerMeta(
+	meta, err := NewServerMeta(
 		http.DefaultClient,
 		panicMetaBackend{},
 		"example.com",
@@ -43,6 +45,7 @@ func TestRunMetaUpdateRecoversFromPanic(t *testing.T) {
 		nil,
 		nil,
 	)
+	require.NoError(t, err)
 
 	update := &metaUpdate{done: make(chan struct{})}
 	meta.updates["org/repo"] = update
@@ -60,3 +63,96 @@ func TestRunMetaUpdateRecoversFromPanic(t *testing.T) {
 	_, exists := meta.updates["org/repo"]
 	assert.False(t, exists)
 }
+
+func TestRouteTableCompiledOncePerCommit(t *testing.T) {
+	backend := newMemoryRepoBackend()
+	backend.set("org", "repo", "c1", map[string]string{"index.html": "hello"})
+	meta := newTestServerMeta(t, backend, noopFilters())
+	ctx := context.Background()
+
+	page, err := meta.GetMeta(ctx, "org", "repo")
+	require.NoError(t, err)
+	table, err := meta.RouteTable("org", "repo", page)
+	require.NoError(t, err)
+
+	// 同一提交重新刷新后复用已编译的路由表
+	cache, _, _ := meta.cache.Load(ctx, "org/repo")
+	cache.RefreshAt = time.Now().Add(-time.Second)
+	require.NoError(t, meta.cache.Store(ctx, "org/repo", cache))
+	page, err = meta.refreshMeta(ctx, "org", "repo")
+	require.NoError(t, err)
+	assert.True(t, page.RefreshAt.After(time.Now()))
+	again, err := meta.RouteTable("org", "repo", page)
+	require.NoError(t, err)
+	assert.Same(t, table, again)
+
+	// 提交 ID 不变而路由规则变化时重新编译
+	backend.set("org", "repo", "c1", map[string]string{".pages.yaml": "routes:\n  - path: drafts/**\n    block: {}\n"})
+	cache.RefreshAt = time.Now().Add(-time.Second)
+	require.NoError(t, meta.cache.Store(ctx, "org/repo", cache))
+	page, err = meta.refreshMeta(ctx, "org", "repo")
+	require.NoError(t, err)
+	again, err = meta.RouteTable("org", "repo", page)
+	require.NoError(t, err)
+	assert.NotSame(t, table, again)
+}
+
+func TestRefreshMetaValidatesRouteParams(t *testing.T) {
+	backend := newMemoryRepoBackend()
+	backend.set("org", "repo", "c1", map[string]string{
+		"index.html": "hello",
+		".pages.yaml": `
+routes:
+- path: "old/**"
+  redirect:
+    code: 200
+`,
+	})
+	noop := func(FilterContext, http.ResponseWriter, *http.Request, NextCall) error { return nil }
+	instances := map[string]FilterInstance{
+		"404":    func(Params) (FilterCall, error) { return noop, nil },
+		"block":  func(Params) (FilterCall, error) { return noop, nil },
+		"direct": func(Params) (FilterCall, error) { return noop, nil },
+		"redirect": func(config Params) (FilterCall, error) {
+			if code, _ := config["code"].(int); code != 0 && (code < 300 || code > 399) {
+				return nil, errors.Errorf("invalid code: %d", code)
+			}
+			return noop, nil
+		},
+	}
+	meta := newTestServerMeta(t, backend, instances)
+
+	_, err := meta.GetMeta(context.Background(), "org", "repo")
+	var cfgErr *PageConfigError
+	require.ErrorAs(t, err, &cfgErr)
+	assert.Equal(t, `line 3: invalid redirect params in route "old/**": invalid code: 200`, cfgErr.Message)
+	assert.Equal(t, "page configuration error", err.Error())
+	cache, found, _ := meta.cache.Load(context.Background(), "org/repo")
+	require.True(t, found)
+	assert.Equal(t, cfgErr.Message, cache.ErrorMsg)
+
+	// 缓存命中时返回相同的配置错误
+	_, err = meta.GetMeta(context.Background(), "org", "repo")
+	require.ErrorAs(t, err, &cfgErr)
+	assert.Equal(t, "c1", cfgErr.CommitID)
+	assert.Contains(t, cfgErr.Detail(), "org/repo@c1")
+}
+
+func TestPageRepoDetection(t *testing.T) {
+	backend := newMemoryRepoBackend()
+	backend.set("org", "clean", "c1", map[string]string{".pages.yaml": "clean_urls: true\n", "about.html": "about"})
+	backend.set("org", "plain", "c1", map[string]string{"about.html": "about"})
+	backend.set("org", "htm", "c1", map[string]string{"index.htm": "home"})
+	meta := newTestServerMeta(t, backend, noopFilters())
+	ctx := context.Background()
+
+	// 仅有 <page>.html 的 clean_urls 站点
+	page, err := meta.GetMeta(ctx, "org", "clean")
+	require.NoError(t, err)
+	assert.True(t, page.CleanURLs)
+
+	_, err = meta.GetMeta(ctx, "org", "plain")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = meta.GetMeta(ctx, "org", "htm")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOrgConfigRepo 默认的组织级配置仓库
+const DefaultOrgConfigRepo = ".pages"
+
+// OrgPageConfig 组织级配置，位于 <owner>/.pages 仓库的 .pages.yaml
+type OrgPageConfig struct {
+	Domains  []string        `yaml:"domains"`  // 绑定至整个组织的域名，路径第一段选择仓库
+	Defaults OrgPageDefaults `yaml:"defaults"` // 组织内所有仓库的默认配置
+}
+
+// OrgPageDefaults 组织默认配置，仓库 .pages.yaml 中显式声明的字段优先
+type OrgPageDefaults struct {
+	Private  bool         `yaml:"private" json:"private"`   // 默认是否私有
+	Security PageSecurity `yaml:"security" json:"security"` // 默认页面安全策略
+	Block    []string     `yaml:"block" json:"block"`       // 始终阻止访问的路径，仓库无法覆盖
+}
+
+func defaultOrgPageDefaults() OrgPageDefaults {
+	return OrgPageDefaults{
+		Security: DefaultPageSecurity(),
+	}
+}
+
+type OrgMetaContent struct {
+	CommitID  string    `json:"commit_id"`  // 配置仓库 COMMIT ID
+	Exists    bool      `json:"exists"`     // 配置仓库是否存在
+	ErrorMsg  string    `json:"error"`      // 配置解析错误
+	RefreshAt time.Time `json:"refresh_at"` // 下次刷新时间
+
+	Domains  []string        `json:"domains"`  // 组织域名
+	Defaults OrgPageDefaults `json:"defaults"` // 组织默认配置
+}
+
+// GetOrgMeta 获取组织级配置，配置仓库不存在时返回空配置；
+// 缓存过期时同一组织的并发请求共用一次刷新
+func (s *ServerMeta) GetOrgMeta(ctx context.Context, owner string) (*OrgMetaContent, error) {
+	if s.OrgRepo == "" {
+		return &OrgMetaContent{}, nil
+	}
+	if cache, found, _ := s.orgCache.Load(ctx, owner); found && time.Now().Before(cache.RefreshAt) {
+		return &cache, nil
+	}
+	update := s.getOrStartOrgUpdate(owner)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-update.done:
+		return update.meta, update.err
+	}
+}
+
+// cachedOrgMeta 读取组织级配置缓存，缓存缺失或过期时在后台刷新
+func (s *ServerMeta) cachedOrgMeta(ctx context.Context, owner string) (*OrgMetaContent, bool) {
+	cache, found, _ := s.orgCache.Load(ctx, owner)
+	if !found || !time.Now().Before(cache.RefreshAt) {
+		s.triggerOrgRefresh(owner)
+	}
+	return &cache, found
+}
+
+func (s *ServerMeta) triggerOrgRefresh(owner string) {
+	s.updatesMu.Lock()
+	if _, ok := s.orgUpdates[owner]; ok {
+		s.updatesMu.Unlock()
+		return
+	}
+	select {
+	case s.refreshSem <- struct{}{}:
+	default:
+		s.updatesMu.Unlock()
+		return
+	}
+	update := &orgUpdate{done: make(chan struct{})}
+	s.orgUpdates[owner] = update
+	s.updatesMu.Unlock()
+
+	go func() {
+		defer func() { <-s.refreshSem }()
+		s.runOrgUpdate(owner, update)
+	}()
+}
+
+func (s *ServerMeta) getOrStartOrgUpdate(owner string) *orgUpdate {
+	s.updatesMu.Lock()
+	if update, ok := s.orgUpdates[owner]; ok {
+		s.updatesMu.Unlock()
+		return update
+	}
+	update := &orgUpdate{done: make(chan struct{})}
+	s.orgUpdates[owner] = update
+	s.updatesMu.Unlock()
+
+	go s.runOrgUpdate(owner, update)
+	return update
+}
+
+func (s *ServerMeta) runOrgUpdate(owner string, update *orgUpdate) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			update.meta = nil
+			update.err = fmt.Errorf("panic while refreshing org metadata: %v", recovered)
+			slog.Error("panic while refreshing org metadata", "owner", owner,
+				"panic", recovered, "stack", string(debug.Stack()))
+		}
+		s.updatesMu.Lock()
+		delete(s.orgUpdates, owner)
+		s.updatesMu.Unlock()
+		close(update.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// 再次检查缓存
+	cache, found, _ := s.orgCache.Load(ctx, owner)
+	if found && time.Now().Before(cache.RefreshAt) {
+		update.meta = &cache
+		return
+	}
+	var previous *OrgMetaContent
+	if found {
+		previous = &cache
+	}
+	update.meta, update.err = s.refreshOrgMeta(ctx, owner, previous)
+}
+
+func (s *ServerMeta) refreshOrgMeta(ctx context.Context, owner string, previous *OrgMetaContent) (*OrgMetaContent, error) {
+	rel := &OrgMetaContent{
+		RefreshAt: time.Now().Add(s.refresh),
+		Defaults:  defaultOrgPageDefaults(),
+	}
+	info, err := s.Meta(ctx, owner, s.OrgRepo)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		rel.Exists = true
+		rel.CommitID = info.ID
+		vfs := NewPageVFS(s.Backend, owner, s.OrgRepo, info.ID)
+		if err = s.parseOrgConfig(ctx, rel, vfs); err != nil {
+			slog.Warn("invalid org config", "owner", owner, "error", err)
+			rel.ErrorMsg = err.Error()
+			rel.Domains = nil
+			rel.Defaults = defaultOrgPageDefaults()
+		}
+	}
+	// 配置仓库不存在且此前未绑定域名时无需写入
+	if rel.Exists || (previous != nil && len(previous.Domains) > 0) {
+		if err = s.Alias.Bind(ctx, rel.Domains, owner, ""); err != nil {
+			slog.Warn("org domain binding error", "owner", owner, "error", err)
+			return nil, err
+		}
+	}
+	_ = s.orgCache.Store(ctx, owner, *rel)
+	return rel, nil
+}
+
+func (s *ServerMeta) parseOrgConfig(ctx context.Context, meta *OrgMetaContent, vfs *PageVFS) error {
+	data, err := vfs.ReadString(ctx, ".pages.yaml")
+	if err != nil {
+		return nil // 配置文件不存在不是错误
+	}
+	cfg := &OrgPageConfig{
+		Defaults: defaultOrgPageDefaults(),
+	}
+	if err = yaml.Unmarshal([]byte(data), cfg); err != nil {
+		return errors.Wrap(err, "parse org .pages.yaml failed")
+	}
+	ApplyPageSecurityDefaults(&cfg.Defaults.Security)
+	domains := make([]string, 0, len(cfg.Domains))
+	for _, item := range cfg.Domains {
+		if item == "" {
+			continue
+		}
+		domain, ok := s.AliasCheck(item)
+		if !ok {
+			return fmt.Errorf("invalid org domain %s", item)
+		}
+		domains = append(domains, domain)
+	}
+	block := make([]string, 0, len(cfg.Defaults.Block))
+	for _, item := range cfg.Defaults.Block {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if _, err = glob.Compile(item); err != nil {
+			return errors.Wrapf(err, "invalid org block pattern: %s", item)
+		}
+		block = append(block, item)
+	}
+	cfg.Defaults.Block = block
+	meta.Domains = domains
+	meta.Defaults = cfg.Defaults
+	return nil
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgDefaultsReevaluatedOnCommitChange(t *testing.T) {
+	backend := newMemoryRepoBackend()
+	backend.set("corp", ".pages", "c1", map[string]string{
+		".pages.yaml": "defaults:\n  security:\n    headers:\n      frame_options: DENY\n",
+	})
+	backend.set("corp", "repo1", "r1", map[string]string{"index.html": "hello"})
+
+	meta := newTestServerMeta(t, backend, nil)
+	ctx := context.Background()
+
+	page, err := meta.GetMeta(ctx, "corp", "repo1")
+	require.NoError(t, err)
+	assert.Equal(t, "c1", page.OrgCommitID)
+	assert.Equal(t, "DENY", page.Security.Headers.FrameOptions)
+
+	// 组织配置仓库提交变化前缓存保持有效
+	assert.True(t, meta.isMetaFresh(ctx, "corp", "repo1", page))
+
+	backend.set("corp", ".pages", "c2", map[string]string{
+		".pages.yaml": "defaults:\n  security:\n    headers:\n      frame_options: SAMEORIGIN\n",
+	})
+	org, _, _ := meta.orgCache.Load(ctx, "corp")
+	org.RefreshAt = time.Now().Add(-time.Second)
+	require.NoError(t, meta.orgCache.Store(ctx, "corp", org))
+
+	// 组织配置过期时仅触发后台刷新，刷新完成后页面缓存过期
+	assert.True(t, meta.isMetaFresh(ctx, "corp", "repo1", page))
+	_, err = meta.GetOrgMeta(ctx, "corp")
+	require.NoError(t, err)
+	assert.False(t, meta.isMetaFresh(ctx, "corp", "repo1", page))
+	page, err = meta.refreshMeta(ctx, "corp", "repo1")
+	require.NoError(t, err)
+	assert.Equal(t, "c2", page.OrgCommitID)
+	assert.Equal(t, "SAMEORIGIN", page.Security.Headers.FrameOptions)
+	assert.True(t, meta.isMetaFresh(ctx, "corp", "repo1", page))
+}
+
+func TestOrgMetaRefreshSingleFlight(t *testing.T) {
+	backend := newMemoryRepoBackend()
+	backend.set("corp", ".pages", "c1", map[string]string{".pages.yaml": "domains: [docs.corp.example]\n"})
+
+	meta := newTestServerMeta(t, backend, nil)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			org, err := meta.GetOrgMeta(ctx, "corp")
+			assert.NoError(t, err)
+			assert.Equal(t, "c1", org.CommitID)
+		}()
+	}
+	wg.Wait()
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	assert.Equal(t, 1, backend.metaCalls["corp/.pages"])
+}
+
+func TestOrgDefaultsPrecedence(t *testing.T) {
+	backend := newMemoryRepoBackend()
+	backend.set("corp", ".pages", "c1", map[string]string{
+		".pages.yaml": `
+defaults:
+  private: true
+  security:
+    cors:
+      origins: [https://a.example.org, https://b.example.org]
+    cookies:
+      enabled: false
+  block: ["drafts/**"]
+`,
+	})
+	backend.set("corp", "repo1", "r1", map[string]string{
+		"index.html": "hello",
+		".pages.yaml": `
+private: false
+security:
+  cors:
+    origins: [https://c.example.org]
+`,
+	})
+	backend.set("corp", "repo2", "r1", map[string]string{"index.html": "hello"})
+
+	meta := newTestServerMeta(t, backend, nil)
+	ctx := context.Background()
+
+	page, err := meta.GetMeta(ctx, "corp", "repo1")
+	require.NoError(t, err)
+	assert.False(t, page.Private)
+	assert.Equal(t, []string{"https://c.example.org"}, page.Security.CORS.Origins)
+	assert.False(t, page.Security.Cookies.Enabled)
+	assert.Contains(t, page.Filters, Filter{Path: "drafts/**", Type: "block", Params: map[string]any{}})
+
+	page, err = meta.GetMeta(ctx, "corp", "repo2")
+	require.NoError(t, err)
+	assert.True(t, page.Private)
+	assert.Equal(t, []string{"https://a.example.org", "https://b.example.org"}, page.Security.CORS.Origins)
+}
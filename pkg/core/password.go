@@ -0,0 +1,414 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+// PasswordPath 口令登录表单
+const PasswordPath = "/.pages/password"
+
+const passwordCookiePrefix = "gitea_pages_password_"
+
+// PagePassword 页面访问口令，hash 与 htpasswd 二选一；
+// 支持 bcrypt 与 argon2 (PHC 格式) 哈希，htpasswd 为仓库内的文件路径，登录时需要填写用户名
+type PagePassword struct {
+	Hash     string `yaml:"hash" json:"hash,omitempty"`
+	Htpasswd string `yaml:"htpasswd" json:"htpasswd,omitempty"`
+}
+
+// Normalize 校验口令配置
+func (p *PagePassword) Normalize() error {
+	p.Hash = strings.TrimSpace(p.Hash)
+	p.Htpasswd = strings.TrimSpace(p.Htpasswd)
+	switch {
+	case p.Hash != "" && p.Htpasswd != "":
+		return errors.New("password hash and htpasswd are mutually exclusive")
+	case p.Hash != "":
+		return checkPasswordHash(p.Hash)
+	case p.Htpasswd != "":
+		cleaned := path.Clean("/" + p.Htpasswd)
+		if cleaned == "/" {
+			return errors.Errorf("invalid htpasswd path %q", p.Htpasswd)
+		}
+		p.Htpasswd = strings.TrimPrefix(cleaned, "/")
+		return nil
+	default:
+		return errors.New("password must declare hash or htpasswd")
+	}
+}
+
+// Scope 口令作用域，绑定仓库与口令配置，修改配置后已签发的 Cookie 失效；
+// htpasswd 文件内容的变化由 Cookie 校验时比对用户的当前哈希处理
+func (p *PagePassword) Scope(owner, repo string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{owner, repo, p.Hash, p.Htpasswd}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Verify 校验用户名与口令，使用 htpasswd 时从页面文件中读取并返回该用户的哈希
+func (p *PagePassword) Verify(ctx context.Context, vfs *PageVFS, user, password string) (string, bool, error) {
+	if p.Htpasswd == "" {
+		return "", VerifyPasswordHash(p.Hash, password), nil
+	}
+	hash, ok, err := p.userHash(ctx, vfs, user)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return hash, VerifyPasswordHash(hash, password), nil
+}
+
+// userHash 返回 htpasswd 中用户当前的哈希
+func (p *PagePassword) userHash(ctx context.Context, vfs *PageVFS, user string) (string, bool, error) {
+	users, err := p.loadHtpasswd(ctx, vfs)
+	if err != nil {
+		return "", false, err
+	}
+	hash, ok := users[user]
+	return hash, ok, nil
+}
+
+func (p *PagePassword) loadHtpasswd(ctx context.Context, vfs *PageVFS) (map[string]string, error) {
+	data, err := vfs.ReadString(ctx, p.Htpasswd)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read htpasswd %s", p.Htpasswd)
+	}
+	return ParseHtpasswd(data)
+}
+
+// ParseHtpasswd 解析 user:hash 格式的 htpasswd 文件，仅接受 bcrypt 与 argon2 哈希
+func ParseHtpasswd(data string) (map[string]string, error) {
+	users := make(map[string]string)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, errors.Errorf("htpasswd line %d: expected user:hash", i+1)
+		}
+		if err := checkPasswordHash(hash); err != nil {
+			return nil, errors.Wrapf(err, "htpasswd line %d", i+1)
+		}
+		users[user] = hash
+	}
+	if len(users) == 0 {
+		return nil, errors.New("htpasswd has no users")
+	}
+	return users, nil
+}
+
+// VerifyPasswordHash 按哈希格式校验口令，参数超出上限的哈希视为不匹配
+func VerifyPasswordHash(hash, password string) bool {
+	if strings.HasPrefix(hash, "$argon2") {
+		params, err := parseArgon2Hash(hash)
+		if err != nil {
+			return false
+		}
+		return subtle.ConstantTimeCompare(params.derive(password), params.key) == 1
+	}
+	if checkPasswordHash(hash) != nil {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// 哈希参数上限，口令由访客提交后即在服务端计算，过大的参数会被用于消耗资源
+const (
+	argon2MaxMemory  = 64 * 1024 // KiB
+	argon2MaxTime    = 10
+	argon2MaxThreads = 16
+	argon2MaxKeyLen  = 64
+	argon2MaxSaltLen = 64
+	bcryptMaxCost    = 14
+)
+
+func checkPasswordHash(hash string) error {
+	if strings.HasPrefix(hash, "$argon2") {
+		_, err := parseArgon2Hash(hash)
+		return err
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return errors.New("unsupported password hash, expected bcrypt or argon2")
+	}
+	if cost > bcryptMaxCost {
+		return errors.Errorf("bcrypt cost %d exceeds limit %d", cost, bcryptMaxCost)
+	}
+	return nil
+}
+
+type argon2Hash struct {
+	variant string
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2Hash 解析 $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key> 格式
+func parseArgon2Hash(hash string) (*argon2Hash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || (parts[1] != "argon2id" && parts[1] != "argon2i") {
+		return nil, errors.New("invalid argon2 hash")
+	}
+	if parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return nil, errors.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	result := &argon2Hash{variant: parts[1]}
+	var threads uint32
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &result.memory, &result.time, &threads); err != nil {
+		return nil, errors.Wrap(err, "invalid argon2 params")
+	}
+	if threads == 0 || result.time == 0 || result.memory == 0 {
+		return nil, errors.New("invalid argon2 params")
+	}
+	if result.memory > argon2MaxMemory || result.time > argon2MaxTime || threads > argon2MaxThreads {
+		return nil, errors.Errorf("argon2 params exceed limit m=%d,t=%d,p=%d", argon2MaxMemory, argon2MaxTime, argon2MaxThreads)
+	}
+	result.threads = uint8(threads)
+	var err error
+	if result.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(result.salt) > argon2MaxSaltLen {
+		return nil, errors.New("invalid argon2 salt")
+	}
+	if result.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(result.key) == 0 || len(result.key) > argon2MaxKeyLen {
+		return nil, errors.New("invalid argon2 key")
+	}
+	return result, nil
+}
+
+func (h *argon2Hash) derive(password string) []byte {
+	if h.variant == "argon2i" {
+		return argon2.Key([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	}
+	return argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+}
+
+// PasswordConfig 按作用域查找页面或路由声明的口令配置
+func (m *PageMetaContent) PasswordConfig(owner, repo, scope string) *PagePassword {
+	for _, password := range m.passwords() {
+		if password.Scope(owner, repo) == scope {
+			return password
+		}
+	}
+	return nil
+}
+
+// passwords 返回页面与 password 路由声明的口令配置
+func (m *PageMetaContent) passwords() []*PagePassword {
+	var result []*PagePassword
+	if m.Password != nil {
+		result = append(result, m.Password)
+	}
+	for _, filter := range m.Filters {
+		if filter.Type != "password" {
+			continue
+		}
+		password := &PagePassword{}
+		if filter.Params.Unmarshal(password) == nil && password.Normalize() == nil {
+			result = append(result, password)
+		}
+	}
+	return result
+}
+
+// PasswordService 口令访问，通过后签发限定于仓库路径的签名 Cookie，与登录会话相互独立
+type PasswordService struct {
+	secret []byte
+	ttl    time.Duration
+	// failures 按客户端统计口令错误次数，超出后暂停校验
+	failures *failureLimiter
+}
+
+// NewPasswordService secret 为空时使用随机密钥，重启后已签发的 Cookie 失效；
+// store 保存口令错误次数，多实例部署时需使用共享存储
+func NewPasswordService(secret []byte, ttl time.Duration, store kv.KV) *PasswordService {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	return &PasswordService{secret: secret, ttl: ttl, failures: newFailureLimiter(store)}
+}
+
+// Require 校验请求携带的口令 Cookie，未通过时 GET 请求跳转到口令表单，其余请求返回 401
+func (s *PasswordService) Require(w http.ResponseWriter, req *http.Request, page *PageContent, password *PagePassword, vfs *PageVFS) bool {
+	scope := password.Scope(page.Owner, page.Repo)
+	if s.verifyCookie(req, scope, password, vfs) {
+		return true
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return false
+	}
+	http.Redirect(w, req, passwordFormURL(req.URL.RequestURI(), scope), http.StatusFound)
+	return false
+}
+
+// Handle 展示 (GET) 或提交 (POST) 口令表单，page 与 password 由 return_to 与 scope 参数解析
+func (s *PasswordService) Handle(w http.ResponseWriter, req *http.Request, page *PageContent, password *PagePassword, vfs *PageVFS) error {
+	w.Header().Set("Cache-Control", "no-store")
+	query := req.URL.Query()
+	returnTo, scope := sanitizeReturnTo(query.Get("return_to")), query.Get("scope")
+	form := passwordForm{
+		Action:   passwordFormURL(returnTo, scope),
+		Username: password.Htpasswd != "",
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return renderPasswordForm(w, http.StatusOK, form)
+	case http.MethodPost:
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return nil
+	}
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	client := RequestInfoFromRequest(req).ClientIP
+	allowed, err := s.failures.Allow(req.Context(), client)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		form.Limited = true
+		return renderPasswordForm(w, http.StatusTooManyRequests, form)
+	}
+	user := req.PostForm.Get("username")
+	credential, ok, err := password.Verify(req.Context(), vfs, user, req.PostForm.Get("password"))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err = s.failures.Fail(req.Context(), client); err != nil {
+			return err
+		}
+		form.Failed = true
+		return renderPasswordForm(w, http.StatusUnauthorized, form)
+	}
+	expireAt := time.Now().Add(s.ttl)
+	cookiePath := page.BasePath
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     passwordCookiePrefix + scope,
+		Value:    s.signCookie(scope, credential, user, expireAt),
+		Path:     cookiePath,
+		HttpOnly: true,
+		Secure:   RequestInfoFromRequest(req).Scheme == "https",
+		SameSite: http.SameSiteLaxMode,
+		Expires:  expireAt,
+	})
+	http.Redirect(w, req, returnTo, http.StatusSeeOther)
+	return nil
+}
+
+// signCookie Cookie 内容为 base64(user|过期时间).签名，签名绑定作用域与 htpasswd 中该用户的哈希，
+// 用户被移除或修改口令后 Cookie 失效
+func (s *PasswordService) signCookie(scope, credential, user string, expireAt time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(user + "|" + strconv.FormatInt(expireAt.Unix(), 10)))
+	return payload + "." + s.sign(scope, credential, payload)
+}
+
+func (s *PasswordService) verifyCookie(req *http.Request, scope string, password *PagePassword, vfs *PageVFS) bool {
+	cookie, err := req.Cookie(passwordCookiePrefix + scope)
+	if err != nil {
+		return false
+	}
+	payload, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return false
+	}
+	index := strings.LastIndexByte(string(data), '|')
+	if index < 0 {
+		return false
+	}
+	var credential string
+	if password.Htpasswd != "" {
+		if credential, ok, err = password.userHash(req.Context(), vfs, string(data[:index])); err != nil || !ok {
+			return false
+		}
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(scope, credential, payload))) {
+		return false
+	}
+	expireAt, err := strconv.ParseInt(string(data[index+1:]), 10, 64)
+	return err == nil && time.Now().Unix() < expireAt
+}
+
+func (s *PasswordService) sign(scope, credential, payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("password\x00" + scope + "\x00" + credential + "\x00" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func passwordFormURL(returnTo, scope string) string {
+	return PasswordPath + "?" + url.Values{"return_to": {returnTo}, "scope": {scope}}.Encode()
+}
+
+type passwordForm struct {
+	Action   string
+	Username bool
+	Failed   bool
+	Limited  bool
+}
+
+var passwordTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+<style>
+body{font-family:system-ui,sans-serif;display:flex;justify-content:center;padding-top:15vh;margin:0}
+form{display:flex;flex-direction:column;gap:.75rem;width:18rem}
+input,button{font:inherit;padding:.5rem}
+.error{color:#b00020}
+</style>
+</head>
+<body>
+<form method="post" action="{{.Action}}">
+<h1>Password required</h1>
+{{if .Limited}}<p class="error">Too many attempts. Try again later.</p>{{else if .Failed}}<p class="error">Incorrect password.</p>{{end}}
+{{if .Username}}<input name="username" autocomplete="username" placeholder="Username" required autofocus>{{end}}
+<input name="password" type="password" autocomplete="current-password" placeholder="Password" required{{if not .Username}} autofocus{{end}}>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+func renderPasswordForm(w http.ResponseWriter, code int, form passwordForm) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	return passwordTemplate.Execute(w, form)
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPasswordHash(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	// htpasswd -B 生成的 $2y$ 前缀
+	apache := "$2y$" + strings.TrimPrefix(string(hash), "$2a$")
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("s3cret"), salt, 1, 64, 1, 32)
+	argon := "$argon2id$v=19$m=64,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(key)
+
+	for _, item := range []string{string(hash), apache, argon} {
+		require.NoError(t, checkPasswordHash(item), item)
+		assert.True(t, VerifyPasswordHash(item, "s3cret"), item)
+		assert.False(t, VerifyPasswordHash(item, "wrong"), item)
+	}
+	for _, item := range []string{"s3cret", "$apr1$abc$def", "{SHA}abc", "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5"} {
+		assert.Error(t, checkPasswordHash(item), item)
+		assert.False(t, VerifyPasswordHash(item, "s3cret"), item)
+	}
+}
+
+func TestPasswordHashLimits(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	salt := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	key := base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+
+	// 参数过大的哈希在配置校验时拒绝，且不会被用于计算
+	for _, item := range []string{
+		strings.Replace(string(hash), "$04$", "$31$", 1),
+		"$argon2id$v=19$m=4194304,t=1,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=64,t=1000,p=1$" + salt + "$" + key,
+		"$argon2id$v=19$m=64,t=1,p=255$" + salt + "$" + key,
+		"$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + base64.RawStdEncoding.EncodeToString(make([]byte, 4096)),
+		"$argon2id$v=19$m=64,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(make([]byte, 4096)) + "$" + key,
+	} {
+		assert.Error(t, checkPasswordHash(item), item)
+		assert.Error(t, (&PagePassword{Hash: item}).Normalize(), item)
+		assert.False(t, VerifyPasswordHash(item, "s3cret"), item)
+	}
+}
+
+func TestPagePasswordNormalize(t *testing.T) {
+	password := &PagePassword{Htpasswd: " ./secrets/../.htpasswd "}
+	require.NoError(t, password.Normalize())
+	assert.Equal(t, ".htpasswd", password.Htpasswd)
+
+	assert.Error(t, (&PagePassword{}).Normalize())
+	assert.Error(t, (&PagePassword{Htpasswd: "/"}).Normalize())
+	assert.Error(t, (&PagePassword{Hash: "plain"}).Normalize())
+	assert.Error(t, (&PagePassword{Hash: "$2y$10$x", Htpasswd: ".htpasswd"}).Normalize())
+
+	// 作用域绑定仓库与配置
+	a := &PagePassword{Hash: "$2y$10$a"}
+	assert.NotEqual(t, a.Scope("org", "repo1"), a.Scope("org", "repo2"))
+	assert.NotEqual(t, a.Scope("org", "repo1"), (&PagePassword{Hash: "$2y$10$b"}).Scope("org", "repo1"))
+}
+
+func TestParseHtpasswd(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	users, err := ParseHtpasswd("# reviewers\n\nalice:" + string(hash) + "\r\nbob:" + string(hash) + "\n")
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.True(t, VerifyPasswordHash(users["bob"], "s3cret"))
+
+	_, err = ParseHtpasswd("alice:$apr1$abc$def\n")
+	assert.ErrorContains(t, err, "line 1")
+	_, err = ParseHtpasswd("alice\n")
+	assert.Error(t, err)
+	_, err = ParseHtpasswd("# empty\n")
+	assert.Error(t, err)
+}
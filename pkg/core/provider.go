@@ -36,3 +36,21 @@ func GetProviderFactory(name string) (ProviderFactory, bool) {
 	factory, ok := value.(ProviderFactory)
 	return factory, ok
 }
+
+// AuthProviderFactory 创建独立于内容 Provider 的认证提供方
+type AuthProviderFactory func(httpClient *http.Client, raw json.RawMessage) (AuthProvider, error)
+
+var authProviderRegistry sync.Map
+
+func RegisterAuthProvider(name string, factory AuthProviderFactory) {
+	authProviderRegistry.Store(name, factory)
+}
+
+func GetAuthProviderFactory(name string) (AuthProviderFactory, bool) {
+	value, ok := authProviderRegistry.Load(name)
+	if !ok {
+		return nil, false
+	}
+	factory, ok := value.(AuthProviderFactory)
+	return factory, ok
+}
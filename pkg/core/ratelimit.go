@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.d7z.net/middleware/kv"
+)
+
+const (
+	// failureLimit 单个客户端在 failureWindow 内允许的失败次数
+	failureLimit  = 10
+	failureWindow = time.Minute
+)
+
+// failureLimiter 按客户端 IP 统计失败次数，超出限制后在窗口内拒绝继续尝试；
+// 计数保存在共享 KV 中，多个实例共用同一限额，窗口自首次失败起算
+type failureLimiter struct {
+	store kv.KV
+}
+
+func newFailureLimiter(store kv.KV) *failureLimiter {
+	return &failureLimiter{store: store}
+}
+
+// Allow 判断客户端是否仍可尝试
+func (l *failureLimiter) Allow(ctx context.Context, client string) (bool, error) {
+	count, _, err := l.load(ctx, client)
+	if err != nil {
+		return false, err
+	}
+	return count < failureLimit, nil
+}
+
+// Fail 记录一次失败，写入冲突时重新读取后重试
+func (l *failureLimiter) Fail(ctx context.Context, client string) error {
+	for {
+		count, raw, err := l.load(ctx, client)
+		if err != nil {
+			return err
+		}
+		var success bool
+		if raw == nil {
+			success, err = l.store.PutIfNotExists(ctx, client, "1", failureWindow)
+		} else {
+			success, err = l.store.CompareAndSwap(ctx, client, *raw, strconv.Itoa(count+1))
+		}
+		if err != nil || success {
+			return err
+		}
+	}
+}
+
+// load 返回失败次数与原始内容，尚无记录时原始内容为 nil
+func (l *failureLimiter) load(ctx context.Context, client string) (int, *string, error) {
+	raw, err := l.store.Get(ctx, client)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	count, _ := strconv.Atoi(raw)
+	return count, &raw, nil
+}
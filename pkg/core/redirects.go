@@ -0,0 +1,228 @@
+package core
+
+import (
+	"bufio"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RedirectsFile 仓库根目录下 Netlify 风格的重定向规则文件
+const RedirectsFile = "_redirects"
+
+var redirectStatus = []int{
+	http.StatusOK,
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusSeeOther,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+	http.StatusNotFound,
+}
+
+// RedirectRule _redirects 中的一条规则
+type RedirectRule struct {
+	Line   int               `json:"line"`
+	Host   string            `json:"host,omitempty"`  // 来源为完整地址时限定的域名
+	From   string            `json:"from"`            // 路由模式，不含开头的 /
+	Query  map[string]string `json:"query,omitempty"` // 查询参数条件，:name 捕获参数值
+	To     string            `json:"to"`
+	Status int               `json:"status"`
+	Force  bool              `json:"force,omitempty"` // 状态码后带 ! 时即使文件存在也生效
+}
+
+// Rewrite 判断规则是否为不改变地址的改写
+func (r *RedirectRule) Rewrite() bool {
+	return r.Status == http.StatusOK || r.Status == http.StatusNotFound
+}
+
+// ParseRedirects 解析 _redirects 文件
+//
+// 每行格式为 `来源 [查询参数条件...] 目标 [状态码[!]]`，# 开头为注释。
+func ParseRedirects(data string) ([]RedirectRule, error) {
+	result := make([]RedirectRule, 0)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := parseRedirectLine(strings.Fields(text))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s line %d", RedirectsFile, line)
+		}
+		rule.Line = line
+		result = append(result, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "read %s failed", RedirectsFile)
+	}
+	return result, nil
+}
+
+func parseRedirectLine(fields []string) (RedirectRule, error) {
+	rule := RedirectRule{Status: http.StatusMovedPermanently}
+	if len(fields) < 2 {
+		return rule, errors.New("missing redirect target")
+	}
+	from := fields[0]
+	if u, ok := parseRedirectURL(from); ok {
+		rule.Host = strings.ToLower(u.Host)
+		from = u.Path
+	}
+	if !strings.HasPrefix(from, "/") {
+		return rule, errors.Errorf("invalid source %q", fields[0])
+	}
+	rule.From = escapeNetlifyPath(strings.Trim(from, "/"))
+	if _, err := CompileRoutePattern(rule.From); err != nil {
+		return rule, errors.Wrapf(err, "invalid source %q", fields[0])
+	}
+	rest := fields[1:]
+	for len(rest) > 0 && isRedirectQuery(rest[0]) {
+		name, value, _ := strings.Cut(rest[0], "=")
+		if rule.Query == nil {
+			rule.Query = make(map[string]string)
+		}
+		rule.Query[name] = value
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		return rule, errors.New("missing redirect target")
+	}
+	rule.To = rest[0]
+	rest = rest[1:]
+	if len(rest) > 0 {
+		code, force := strings.CutSuffix(rest[0], "!")
+		status, err := strconv.Atoi(code)
+		if err != nil || !slices.Contains(redirectStatus, status) {
+			return rule, errors.Errorf("invalid status %q", rest[0])
+		}
+		rule.Status = status
+		rule.Force = force
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		return rule, errors.Errorf("unsupported condition %q", rest[0])
+	}
+	_, absolute := parseRedirectURL(rule.To)
+	switch {
+	case !absolute && !strings.HasPrefix(rule.To, "/"):
+		return rule, errors.Errorf("invalid target %q", rule.To)
+	case absolute && rule.Rewrite():
+		return rule, errors.Errorf("proxy to %q is not supported", rule.To)
+	}
+	return rule, nil
+}
+
+func parseRedirectURL(value string) (*url.URL, bool) {
+	if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+		return nil, false
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+func isRedirectQuery(field string) bool {
+	name, _, ok := strings.Cut(field, "=")
+	return ok && name != "" && !strings.HasPrefix(field, "/") && !strings.Contains(field, "://")
+}
+
+// escapeNetlifyPath 转义 * 与 :name 以外的 glob 语法
+func escapeNetlifyPath(path string) string {
+	var result strings.Builder
+	for _, c := range path {
+		if strings.ContainsRune(`?[]{},\`, c) {
+			result.WriteByte('\\')
+		}
+		result.WriteRune(c)
+	}
+	return result.String()
+}
+
+// RedirectTable 编译后的 _redirects 规则，按来源开头的字面量路径段建立前缀树
+type RedirectTable struct {
+	root *prefixTree[*redirectEntry]
+	size int
+}
+
+type redirectEntry struct {
+	*RedirectRule
+	pattern *RoutePattern
+	index   int
+}
+
+// NewRedirectTable 编译重定向规则
+func NewRedirectTable(rules []RedirectRule) (*RedirectTable, error) {
+	table := &RedirectTable{root: &prefixTree[*redirectEntry]{}, size: len(rules)}
+	for i := range rules {
+		pattern, err := CompileRoutePattern(rules[i].From)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s line %d", RedirectsFile, rules[i].Line)
+		}
+		table.root.insert(rules[i].From, &redirectEntry{
+			RedirectRule: &rules[i],
+			pattern:      pattern,
+			index:        i,
+		})
+	}
+	return table, nil
+}
+
+// Len 返回规则数量
+func (t *RedirectTable) Len() int {
+	return t.size
+}
+
+// Match 返回首条命中的规则与捕获的参数，path 不含开头与结尾的 /
+func (t *RedirectTable) Match(host, path string, query url.Values) (*RedirectRule, RouteParams, bool) {
+	var (
+		hit    *redirectEntry
+		params RouteParams
+	)
+	for _, entry := range t.root.collect(path) {
+		if hit != nil && entry.index > hit.index {
+			continue
+		}
+		if entry.Host != "" && entry.Host != host {
+			continue
+		}
+		captured, ok := entry.pattern.Match(path)
+		if !ok {
+			continue
+		}
+		if captured, ok = matchRedirectQuery(entry.Query, query, captured); ok {
+			hit, params = entry, captured
+		}
+	}
+	if hit == nil {
+		return nil, nil, false
+	}
+	return hit.RedirectRule, params, true
+}
+
+func matchRedirectQuery(conditions map[string]string, query url.Values, params RouteParams) (RouteParams, bool) {
+	for name, expected := range conditions {
+		if !query.Has(name) {
+			return nil, false
+		}
+		value := query.Get(name)
+		if capture, ok := strings.CutPrefix(expected, ":"); ok && capture != "" {
+			if params == nil {
+				params = make(RouteParams, len(conditions))
+			}
+			params[capture] = value
+		} else if value != expected {
+			return nil, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRedirects(t *testing.T) {
+	rules, err := ParseRedirects(`
+# comment
+/home              /
+/news/*            /blog/:splat
+/posts/:year/:slug /blog/:year/:slug 302
+/store id=:id      /products/:id     301!
+/app/*             /app/index.html   200
+https://old.example.com/* https://new.example.com/:splat 308
+/weird[1]          /ok
+`)
+	require.NoError(t, err)
+	require.Len(t, rules, 7)
+	assert.Equal(t, RedirectRule{Line: 3, From: "home", To: "/", Status: http.StatusMovedPermanently}, rules[0])
+	assert.Equal(t, "news/*", rules[1].From)
+	assert.Equal(t, http.StatusFound, rules[2].Status)
+	assert.Equal(t, map[string]string{"id": ":id"}, rules[3].Query)
+	assert.True(t, rules[3].Force)
+	assert.True(t, rules[4].Rewrite())
+	assert.Equal(t, "old.example.com", rules[5].Host)
+	assert.Equal(t, `weird\[1\]`, rules[6].From)
+}
+
+func TestParseRedirectsErrors(t *testing.T) {
+	tests := map[string]string{
+		"/only":                      "missing redirect target",
+		"/a /b 418":                  "invalid status",
+		"/a /b 302 Country=us":       "unsupported condition",
+		"/a https://example.com 200": "proxy",
+		"a /b":                       "invalid source",
+		"/a b":                       "invalid target",
+	}
+	for line, expected := range tests {
+		_, err := ParseRedirects("\n" + line)
+		require.Error(t, err, line)
+		assert.Contains(t, err.Error(), "_redirects line 2", line)
+		assert.Contains(t, err.Error(), expected, line)
+	}
+}
+
+func TestRedirectTableMatch(t *testing.T) {
+	rules, err := ParseRedirects(`
+/docs/*            /v1/:splat
+/docs/latest/*     /v2/:splat
+/store id=:id      /products/:id
+/store             /products
+https://old.example.com/* /:splat
+/*                 /index.html 200
+`)
+	require.NoError(t, err)
+	table, err := NewRedirectTable(rules)
+	require.NoError(t, err)
+	assert.Equal(t, 6, table.Len())
+
+	rule, params, ok := table.Match("example.com", "docs/latest/guide", nil)
+	require.True(t, ok)
+	// 按行号取首条命中的规则
+	assert.Equal(t, 2, rule.Line)
+	assert.Equal(t, "/v1/latest/guide", params.Expand(rule.To))
+
+	rule, params, ok = table.Match("example.com", "store", url.Values{"id": {"42"}})
+	require.True(t, ok)
+	assert.Equal(t, "/products/42", params.Expand(rule.To))
+
+	rule, _, ok = table.Match("example.com", "store", nil)
+	require.True(t, ok)
+	assert.Equal(t, "/products", rule.To)
+
+	rule, _, ok = table.Match("old.example.com", "a/b", nil)
+	require.True(t, ok)
+	assert.Equal(t, 6, rule.Line)
+
+	rule, _, ok = table.Match("example.com", "a/b", nil)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusOK, rule.Status)
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+)
+
+var regexpToken = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+// RouteMatch 路由附加匹配条件，所有声明的条件都满足时路由才生效
+type RouteMatch struct {
+	Methods []string          `yaml:"methods" json:"methods,omitempty"` // 请求方法，GET 同时匹配 HEAD
+	Hosts   []string          `yaml:"hosts" json:"hosts,omitempty"`     // 请求域名 glob，以 . 分隔
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"` // 请求头 glob，空值表示存在即可
+	Query   map[string]string `yaml:"query" json:"query,omitempty"`     // 查询参数 glob，空值表示存在即可
+}
+
+// Normalize 校验并规范化匹配条件
+func (m *RouteMatch) Normalize() error {
+	if m == nil {
+		return nil
+	}
+	for i, method := range m.Methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if !regexpToken.MatchString(method) {
+			return errors.Errorf("invalid method %q", m.Methods[i])
+		}
+		m.Methods[i] = method
+	}
+	for i, host := range m.Hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" {
+			return errors.New("host pattern cannot be empty")
+		}
+		if _, err := glob.Compile(host, '.'); err != nil {
+			return errors.Wrapf(err, "invalid host pattern %q", m.Hosts[i])
+		}
+		m.Hosts[i] = host
+	}
+	if len(m.Headers) > 0 {
+		headers := make(map[string]string, len(m.Headers))
+		for name, pattern := range m.Headers {
+			if !regexpToken.MatchString(name) {
+				return errors.Errorf("invalid header name %q", name)
+			}
+			if _, err := glob.Compile(pattern); err != nil {
+				return errors.Wrapf(err, "invalid header pattern %q", pattern)
+			}
+			headers[http.CanonicalHeaderKey(name)] = pattern
+		}
+		m.Headers = headers
+	}
+	for name, pattern := range m.Query {
+		if name == "" {
+			return errors.New("query name cannot be empty")
+		}
+		if _, err := glob.Compile(pattern); err != nil {
+			return errors.Wrapf(err, "invalid query pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Matches 判断请求是否满足匹配条件，compile 用于复用已编译的 glob
+func (m *RouteMatch) Matches(request *http.Request, host string, compile func(pattern string, separators ...rune) (glob.Glob, error)) bool {
+	if m == nil {
+		return true
+	}
+	if len(m.Methods) > 0 && !m.matchMethod(request.Method) {
+		return false
+	}
+	if len(m.Hosts) > 0 {
+		matched := false
+		for _, pattern := range m.Hosts {
+			if g, err := compile(pattern, '.'); err == nil && g.Match(host) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for name, pattern := range m.Headers {
+		values, ok := request.Header[name]
+		if !ok || !matchAnyValue(values, pattern, compile) {
+			return false
+		}
+	}
+	if len(m.Query) > 0 {
+		query := request.URL.Query()
+		for name, pattern := range m.Query {
+			values, ok := query[name]
+			if !ok || !matchAnyValue(values, pattern, compile) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (m *RouteMatch) matchMethod(method string) bool {
+	for _, item := range m.Methods {
+		if item == method || (item == http.MethodGet && method == http.MethodHead) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyValue(values []string, pattern string, compile func(pattern string, separators ...rune) (glob.Glob, error)) bool {
+	if pattern == "" {
+		return true
+	}
+	g, err := compile(pattern)
+	if err != nil {
+		return false
+	}
+	for _, value := range values {
+		if g.Match(value) {
+			return true
+		}
+	}
+	return false
+}
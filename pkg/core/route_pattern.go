@@ -0,0 +1,177 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+)
+
+// RouteSplat 路由中首个通配符捕获内容对应的参数名
+const RouteSplat = "splat"
+
+var (
+	regexpRouteParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+	regexpRouteParamRef  = regexp.MustCompile(`:[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+// RouteParams 路由匹配时捕获的参数
+type RouteParams map[string]string
+
+// Expand 将字符串中的 :name 替换为捕获的参数，未捕获的名称保持原样
+func (p RouteParams) Expand(value string) string {
+	if len(p) == 0 || !strings.Contains(value, ":") {
+		return value
+	}
+	return regexpRouteParamRef.ReplaceAllStringFunc(value, func(ref string) string {
+		if item, ok := p[ref[1:]]; ok {
+			return item
+		}
+		return ref
+	})
+}
+
+// HasRouteParams 判断字符串中是否引用了路由参数
+func HasRouteParams(value string) bool {
+	return regexpRouteParamRef.MatchString(value)
+}
+
+// RoutePattern 已编译的路由匹配模式
+//
+// 在 glob 语法基础上，以段开头的 :name 匹配单个路径段，:name* 匹配剩余路径，
+// 首个 * 或 ** 通配符的内容以 splat 捕获。
+type RoutePattern struct {
+	glob   glob.Glob      // 不含命名参数时沿用 glob 匹配
+	regexp *regexp.Regexp // 用于捕获参数
+	names  []string
+}
+
+// CompileRoutePattern 编译路由匹配模式
+func CompileRoutePattern(pattern string) (*RoutePattern, error) {
+	expr, names, named, err := translateRoutePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	result := &RoutePattern{names: names}
+	if !named {
+		if result.glob, err = glob.Compile(pattern); err != nil {
+			return nil, err
+		}
+	}
+	if len(names) > 0 {
+		if result.regexp, err = regexp.Compile(expr); err != nil {
+			return nil, errors.Wrapf(err, "invalid route pattern %q", pattern)
+		}
+	}
+	return result, nil
+}
+
+// Match 匹配路径并返回捕获的参数
+func (p *RoutePattern) Match(path string) (RouteParams, bool) {
+	if p.glob != nil && !p.glob.Match(path) {
+		return nil, false
+	}
+	if p.regexp == nil {
+		return nil, true
+	}
+	values := p.regexp.FindStringSubmatch(path)
+	if values == nil {
+		// 仅含通配符时以 glob 结果为准
+		return nil, p.glob != nil
+	}
+	params := make(RouteParams, len(p.names))
+	for i, name := range p.names {
+		params[name] = values[i+1]
+	}
+	return params, true
+}
+
+func translateRoutePattern(pattern string) (string, []string, bool, error) {
+	var (
+		expr  strings.Builder
+		names []string
+		named bool
+		splat bool
+		depth int
+	)
+	addName := func(name string) error {
+		for _, item := range names {
+			if item == name {
+				return errors.Errorf("duplicate route parameter %q in %q", name, pattern)
+			}
+		}
+		names = append(names, name)
+		return nil
+	}
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == ':' && (i == 0 || pattern[i-1] == '/'):
+			name := regexpRouteParamName.FindString(pattern[i+1:])
+			if name == "" {
+				expr.WriteString(":")
+				continue
+			}
+			if name == RouteSplat {
+				return "", nil, false, errors.Errorf("route parameter name %q is reserved", name)
+			}
+			if err := addName(name); err != nil {
+				return "", nil, false, err
+			}
+			named = true
+			i += len(name)
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				expr.WriteString("(.*)")
+			} else {
+				expr.WriteString("([^/]+)")
+			}
+		case c == '*':
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+			}
+			if splat {
+				expr.WriteString(".*")
+				continue
+			}
+			splat = true
+			names = append(names, RouteSplat)
+			expr.WriteString("(.*)")
+		case c == '?':
+			expr.WriteString(".")
+		case c == '\\':
+			if i+1 < len(pattern) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", nil, false, errors.Errorf("unclosed character class in %q", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '{':
+			depth++
+			expr.WriteString("(?:")
+		case c == '}' && depth > 0:
+			depth--
+			expr.WriteString(")")
+		case c == ',' && depth > 0:
+			expr.WriteString("|")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	if depth > 0 {
+		return "", nil, false, errors.Errorf("unclosed alternation in %q", pattern)
+	}
+	expr.WriteString("$")
+	return expr.String(), names, named, nil
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutePatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matched bool
+		params  RouteParams
+	}{
+		{pattern: "**", path: "index.html", matched: true, params: RouteParams{"splat": "index.html"}},
+		{pattern: "index.html", path: "index.html", matched: true},
+		{pattern: "api/*", path: "api/v1/users", matched: true, params: RouteParams{"splat": "v1/users"}},
+		{pattern: "posts/:year/:slug", path: "posts/2024/hello", matched: true, params: RouteParams{"year": "2024", "slug": "hello"}},
+		{pattern: "posts/:year/:slug", path: "posts/2024/hello/index.html", matched: false},
+		{pattern: "posts/:year/:slug", path: "posts/2024", matched: false},
+		{pattern: "docs/:version/:rest*", path: "docs/v2/guide/intro.html", matched: true, params: RouteParams{"version": "v2", "rest": "guide/intro.html"}},
+		{pattern: "users/:id/**", path: "users/42/profile/index.html", matched: true, params: RouteParams{"id": "42", "splat": "profile/index.html"}},
+		{pattern: "files/:name.[jp][pn]g", path: "files/logo.png", matched: true, params: RouteParams{"name": "logo"}},
+		{pattern: "a/:x/{b,c}.html", path: "a/1/c.html", matched: true, params: RouteParams{"x": "1"}},
+		{pattern: "a/:x/{b,c}.html", path: "a/1/d.html", matched: false},
+		{pattern: "a/\\:x", path: "a/:x", matched: true},
+	}
+	for _, tt := range tests {
+		pattern, err := CompileRoutePattern(tt.pattern)
+		require.NoError(t, err, tt.pattern)
+		params, ok := pattern.Match(tt.path)
+		assert.Equal(t, tt.matched, ok, "%s ~ %s", tt.pattern, tt.path)
+		if tt.matched && tt.params != nil {
+			assert.Equal(t, tt.params, params, "%s ~ %s", tt.pattern, tt.path)
+		}
+	}
+}
+
+func TestRoutePatternInvalid(t *testing.T) {
+	for _, pattern := range []string{"a/:id/:id", "a/:splat", "a/[bc", "a/{b"} {
+		_, err := CompileRoutePattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestRouteParamsExpand(t *testing.T) {
+	params := RouteParams{"year": "2024", "slug": "hello"}
+	assert.Equal(t, "/blog/2024/hello", params.Expand("/blog/:year/:slug"))
+	assert.Equal(t, "/blog/:missing", params.Expand("/blog/:missing"))
+	assert.Equal(t, "https://host:8443/2024", params.Expand("https://host:8443/:year"))
+	assert.Equal(t, "/a/:b", RouteParams(nil).Expand("/a/:b"))
+	assert.True(t, HasRouteParams("/users/:id"))
+	assert.False(t, HasRouteParams("https://host:8443/users"))
+}
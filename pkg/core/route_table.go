@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Route 已编译的路由
+type Route struct {
+	Filter
+	Pattern *RoutePattern
+	Call    FilterCall
+	Err     error // 过滤器实例化失败时在命中后返回
+
+	index int
+}
+
+// guardFilters 限制访问的过滤器，目录页以不带结尾斜杠的地址访问时同样需要生效
+var guardFilters = map[string]bool{"auth": true, "block": true, "password": true}
+
+// RouteHit 命中的路由与捕获的参数
+type RouteHit struct {
+	*Route
+	RouteParams RouteParams
+}
+
+// RouteTable 按提交编译的路由表，路由模式开头的字面量路径段组成前缀树，
+// 匹配时只需检查请求路径途经节点上的路由
+type RouteTable struct {
+	root   *prefixTree[*Route]
+	routes []*Route
+}
+
+// prefixTree 以模式开头的字面量路径段索引条目
+type prefixTree[T any] struct {
+	children map[string]*prefixTree[T]
+	items    []T
+}
+
+// NewRouteTable 编译路由模式并实例化过滤器
+func NewRouteTable(filters []Filter, instances map[string]FilterInstance) (*RouteTable, error) {
+	table := &RouteTable{
+		root:   &prefixTree[*Route]{},
+		routes: make([]*Route, 0, len(filters)),
+	}
+	for i, filter := range filters {
+		pattern, err := CompileRoutePattern(filter.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid route pattern: %s", filter.Path)
+		}
+		route := &Route{
+			Filter:  filter,
+			Pattern: pattern,
+			index:   i,
+		}
+		if instance := instances[filter.Type]; instance == nil {
+			route.Err = fmt.Errorf("filter %q became unavailable after metadata validation", filter.Type)
+		} else {
+			route.Call, route.Err = instance(filter.Params)
+		}
+		table.routes = append(table.routes, route)
+		table.root.insert(filter.Path, route)
+	}
+	return table, nil
+}
+
+// Routes 返回全部路由，按声明顺序排列
+func (t *RouteTable) Routes() []*Route {
+	return t.routes
+}
+
+// Match 返回命中路径的路由，按声明顺序排列，accept 用于附加条件过滤
+func (t *RouteTable) Match(path string, accept func(route *Route) bool) []RouteHit {
+	candidates := t.root.collect(path)
+	if len(candidates) > len(t.root.items) {
+		slices.SortFunc(candidates, func(a, b *Route) int {
+			return a.index - b.index
+		})
+	}
+	hits := make([]RouteHit, 0, len(candidates))
+	for _, route := range candidates {
+		params, ok := route.Pattern.Match(path)
+		if !ok || (accept != nil && !accept(route)) {
+			continue
+		}
+		hits = append(hits, RouteHit{Route: route, RouteParams: params})
+	}
+	return hits
+}
+
+// MatchPage 与 Match 相同，另外以路径作为目录时的 index.html 匹配限制访问的路由，
+// 避免 trailing_slash: never 下以 /admin 访问 admin/index.html 时绕过 admin/** 的规则
+func (t *RouteTable) MatchPage(path string, accept func(route *Route) bool) []RouteHit {
+	hits := t.Match(path, accept)
+	if path == "index.html" || strings.HasSuffix(path, "/index.html") {
+		return hits
+	}
+	added := false
+	for _, hit := range t.Match(path+"/index.html", accept) {
+		if !guardFilters[hit.Type] || slices.ContainsFunc(hits, func(item RouteHit) bool {
+			return item.Route == hit.Route
+		}) {
+			continue
+		}
+		hits = append(hits, hit)
+		added = true
+	}
+	if added {
+		slices.SortFunc(hits, func(a, b RouteHit) int {
+			return a.index - b.index
+		})
+	}
+	return hits
+}
+
+func (n *prefixTree[T]) insert(pattern string, item T) {
+	node := n
+	for _, segment := range routeLiteralSegments(pattern) {
+		if node.children == nil {
+			node.children = make(map[string]*prefixTree[T])
+		}
+		child := node.children[segment]
+		if child == nil {
+			child = &prefixTree[T]{}
+			node.children[segment] = child
+		}
+		node = child
+	}
+	node.items = append(node.items, item)
+}
+
+// collect 返回路径途经节点上的全部条目，不同节点间的条目顺序需由调用方重新排序
+func (n *prefixTree[T]) collect(path string) []T {
+	result := n.items
+	node := n
+	for _, segment := range strings.Split(path, "/") {
+		if node = node.children[segment]; node == nil {
+			break
+		}
+		result = append(result[:len(result):len(result)], node.items...)
+	}
+	return result
+}
+
+// routeLiteralSegments 返回模式开头不含通配符与参数的完整路径段
+func routeLiteralSegments(pattern string) []string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if segment == "" || strings.ContainsAny(segment, `*?[]{},\`) || strings.HasPrefix(segment, ":") {
+			return segments[:i]
+		}
+	}
+	return segments
+}
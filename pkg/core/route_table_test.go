@@ -0,0 +1,78 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteTableMatchOrder(t *testing.T) {
+	noop := func(Params) (FilterCall, error) {
+		return func(FilterContext, http.ResponseWriter, *http.Request, NextCall) error { return nil }, nil
+	}
+	instances := map[string]FilterInstance{"a": noop, "b": noop}
+	table, err := NewRouteTable([]Filter{
+		{Path: "**", Type: "a"},
+		{Path: "docs/**", Type: "a"},
+		{Path: "docs/api/:name", Type: "b"},
+		{Path: "*.html", Type: "b"},
+		{Path: "docs/api/index.html", Type: "a"},
+		{Path: "blog/**", Type: "a"},
+		{Path: "docs/**", Type: "missing"},
+	}, instances)
+	require.NoError(t, err)
+	assert.Len(t, table.Routes(), 7)
+
+	paths := func(hits []RouteHit) []string {
+		result := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			result = append(result, hit.Type+":"+hit.Path)
+		}
+		return result
+	}
+	hits := table.Match("docs/api/index.html", nil)
+	assert.Equal(t, []string{
+		"a:**", "a:docs/**", "b:docs/api/:name", "b:*.html", "a:docs/api/index.html", "missing:docs/**",
+	}, paths(hits))
+	assert.Equal(t, RouteParams{"name": "index.html"}, hits[2].RouteParams)
+	assert.Error(t, hits[5].Err)
+	assert.NotNil(t, hits[0].Call)
+
+	assert.Equal(t, []string{"a:**", "b:*.html"}, paths(table.Match("index.html", nil)))
+	assert.Equal(t, []string{"a:**"}, paths(table.Match("docs", nil)))
+
+	hits = table.Match("blog/post.html", func(route *Route) bool { return route.Type != "b" })
+	assert.Equal(t, []string{"a:**", "a:blog/**"}, paths(hits))
+}
+
+func TestRouteTableMatchPageDirectoryIndex(t *testing.T) {
+	noop := func(Params) (FilterCall, error) { return nil, nil }
+	instances := map[string]FilterInstance{"auth": noop, "block": noop, "direct": noop, "template": noop}
+	table, err := NewRouteTable([]Filter{
+		{Path: "admin/**", Type: "auth"},
+		{Path: "**", Type: "direct"},
+		{Path: "**/*.html", Type: "template"},
+		{Path: "secret/**", Type: "block"},
+	}, instances)
+	require.NoError(t, err)
+	types := func(hits []RouteHit) []string {
+		result := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			result = append(result, hit.Type)
+		}
+		return result
+	}
+
+	// 目录页不带结尾斜杠时仅追加限制访问的路由
+	assert.Equal(t, []string{"auth", "direct"}, types(table.MatchPage("admin", nil)))
+	assert.Equal(t, []string{"direct", "block"}, types(table.MatchPage("secret", nil)))
+	assert.Equal(t, []string{"auth", "direct", "template"}, types(table.MatchPage("admin/index.html", nil)))
+	assert.Equal(t, []string{"direct"}, types(table.MatchPage("about", nil)))
+}
+
+func TestRouteTableInvalidPattern(t *testing.T) {
+	_, err := NewRouteTable([]Filter{{Path: "a/:id/:id", Type: "a"}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatusPathPrefix 页面状态查询接口，路径为 /.pages/status/<owner>/<repo>
+const StatusPathPrefix = "/.pages/status/"
+
+const (
+	PageStatusOK       = "ok"
+	PageStatusError    = "error"
+	PageStatusNotFound = "not_found"
+)
+
+// PageConfigError 页面配置解析或校验失败
+//
+// Error 仅返回通用描述，完整错误通过 Detail 获取，只应展示给拥有仓库写权限的用户。
+type PageConfigError struct {
+	Owner    string
+	Repo     string
+	CommitID string
+	Message  string
+}
+
+func (e *PageConfigError) Error() string {
+	return "page configuration error"
+}
+
+// Detail 返回包含行号等信息的完整错误
+func (e *PageConfigError) Detail() string {
+	return fmt.Sprintf("invalid page configuration in %s/%s@%s: %s", e.Owner, e.Repo, e.CommitID, e.Message)
+}
+
+func newPageConfigError(owner, repo string, meta *PageMetaContent) *PageConfigError {
+	return &PageConfigError{
+		Owner:    owner,
+		Repo:     repo,
+		CommitID: meta.CommitID,
+		Message:  meta.ErrorMsg,
+	}
+}
+
+// PageStatus 状态查询接口返回内容
+type PageStatus struct {
+	Owner    string `json:"owner"`
+	Repo     string `json:"repo"`
+	Status   string `json:"status"`
+	CommitID string `json:"commit_id,omitempty"`
+	Error    string `json:"error,omitempty"` // 配置错误详情，仅对有写权限的用户返回
+}
+
+// ParseStatusPath 解析状态查询路径中的仓库
+func ParseStatusPath(path string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(path, StatusPathPrefix)
+	if !ok {
+		return "", "", false
+	}
+	owner, repo, ok := strings.Cut(strings.TrimSuffix(rest, "/"), "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", false
+	}
+	return owner, repo, true
+}
@@ -0,0 +1,36 @@
+package filters
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+// FilterInstAuth 命中的路径要求登录与授权，未声明规则时沿用页面访问规则或仓库读取权限
+func FilterInstAuth(_ core.GlobalFilterInit) (core.FilterInstance, error) {
+	return func(config core.Params) (core.FilterCall, error) {
+		var access *core.PageAccess
+		if len(config) > 0 {
+			access = &core.PageAccess{}
+			if err := config.Unmarshal(access); err != nil {
+				return nil, err
+			}
+			if err := access.Normalize(); err != nil {
+				return nil, err
+			}
+		}
+		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
+			if ctx.Authorize == nil {
+				return errors.New("auth is not supported in this context")
+			}
+			allowed, err := ctx.Authorize(writer, request, access)
+			if err != nil || !allowed {
+				return err
+			}
+			// 已登录的响应由 applyCacheControl 标记为 private
+			ctx.Auth = core.AuthInfoFromContext(request.Context())
+			return next(ctx, writer, request)
+		}, nil
+	}, nil
+}
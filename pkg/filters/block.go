@@ -1,6 +1,7 @@
 package filters
 
 import (
+	"fmt"
 	"net/http"
 	"os"
 
@@ -17,7 +18,13 @@ func FilterInstBlock(_ core.GlobalFilterInit) (core.FilterInstance, error) {
 			return nil, err
 		}
 		if param.Code == 0 {
-			return nil, os.ErrNotExist
+			// 未指定状态码时按文件不存在处理
+			return func(core.FilterContext, http.ResponseWriter, *http.Request, core.NextCall) error {
+				return os.ErrNotExist
+			}, nil
+		}
+		if param.Code < 100 || param.Code > 599 {
+			return nil, fmt.Errorf("invalid code: %d", param.Code)
 		}
 		if param.Message == "" {
 			param.Message = http.StatusText(param.Code)
@@ -11,6 +11,12 @@ import (
 var registeredFilters = map[string]core.GlobalFilter{
 	"block":         FilterInstBlock,
 	"redirect":      FilterInstRedirect,
+	"redirects":     FilterInstRedirects,
+	"headers":       FilterInstHeaders,
+	"rewrite":       FilterInstRewrite,
+	"i18n":          FilterInstI18n,
+	"auth":          FilterInstAuth,
+	"password":      FilterInstPassword,
 	"direct":        FilterInstDirect,
 	"reverse_proxy": FilterInstProxy,
 	"404":           FilterInstDefaultNotFound,
@@ -4,12 +4,24 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	pathpkg "path"
 	"strings"
 
 	"github.com/pkg/errors"
 	"gopkg.d7z.net/gitea-pages/pkg/core"
 )
 
+const (
+	directFile  = iota // 按原路径提供的文件
+	directPage         // clean_urls 下省略扩展名的 .html 页面
+	directIndex        // 目录首页
+)
+
+type directCandidate struct {
+	path string
+	kind int
+}
+
 func FilterInstDirect(init core.GlobalFilterInit) (core.FilterInstance, error) {
 	return func(config core.Params) (core.FilterCall, error) {
 		var param struct {
@@ -18,7 +30,7 @@ func FilterInstDirect(init core.GlobalFilterInit) (core.FilterInstance, error) {
 		if err := config.Unmarshal(&param); err != nil {
 			return nil, err
 		}
-		param.Prefix = strings.Trim(param.Prefix, "/") + "/"
+		param.Prefix = strings.Trim(param.Prefix, "/")
 		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
 			err := next(ctx, writer, request)
 			if (err != nil && !errors.Is(err, os.ErrNotExist)) || err == nil {
@@ -28,35 +40,101 @@ func FilterInstDirect(init core.GlobalFilterInit) (core.FilterInstance, error) {
 				http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
 				return nil
 			}
-			path := param.Prefix + strings.TrimSuffix(ctx.Path, "/")
-			slog.Debug("direct fetch", "path", path)
-			resp, err := ctx.NativeOpen(request.Context(), path, nil)
-			if err != nil {
-				if resp != nil {
-					resp.Body.Close()
-				}
-				if !errors.Is(err, os.ErrNotExist) {
-					slog.Debug("error", "error", err)
-					return err
+			prefix := strings.Trim(ctx.RouteParams.Expand(param.Prefix), "/") + "/"
+			// 内部改写后的路径与请求地址无关，不做规范化跳转
+			canonical := ctx.Path == directRequestPath(ctx, request)
+			for _, candidate := range directCandidates(ctx, request, canonical) {
+				path := prefix + candidate.path
+				slog.Debug("direct fetch", "path", path)
+				resp, err := ctx.NativeOpen(request.Context(), path, nil)
+				if err != nil {
+					if resp != nil {
+						resp.Body.Close()
+					}
+					if !errors.Is(err, os.ErrNotExist) {
+						slog.Debug("error", "error", err)
+						return err
+					}
+					continue
 				}
-				exists, e := ctx.Exists(ctx, path+"/index.html")
-				if e != nil {
-					return err
+				if resp == nil {
+					continue
 				}
-				if exists {
-					http.Redirect(writer, request, strings.TrimSuffix(request.URL.Path, "/")+"/", http.StatusFound)
-					return nil
+				defer resp.Body.Close()
+				if canonical {
+					if target := directCanonicalPath(ctx, request.URL.Path, candidate.kind); target != request.URL.Path {
+						if request.URL.RawQuery != "" {
+							target += "?" + request.URL.RawQuery
+						}
+						http.Redirect(writer, request, target, http.StatusFound)
+						return nil
+					}
 				}
-				return err
-			}
-			if resp == nil {
-				return os.ErrNotExist
+				return writeStaticFileResponse(ctx, writer, request, path, resp, init.Server.StaticCacheControl)
 			}
-			defer resp.Body.Close()
-			if err != nil {
-				return err
-			}
-			return writeStaticFileResponse(ctx, writer, request, path, resp, init.Server.StaticCacheControl)
+			return os.ErrNotExist
 		}, nil
 	}, nil
 }
+
+// directRequestPath 返回请求地址对应的页面路径
+func directRequestPath(ctx core.FilterContext, request *http.Request) string {
+	path := strings.TrimPrefix(strings.TrimPrefix(request.URL.Path, ctx.BasePath), "/")
+	if strings.HasSuffix(path, "/") || path == "" {
+		path += "index.html"
+	}
+	return path
+}
+
+// directCandidates 按优先级返回页面路径可能对应的文件
+func directCandidates(ctx core.FilterContext, request *http.Request, canonical bool) []directCandidate {
+	name := strings.TrimSuffix(ctx.Path, "/")
+	if dir, ok := strings.CutSuffix(name, "index.html"); ok && (strings.HasSuffix(request.URL.Path, "/") || !canonical) {
+		result := []directCandidate{{path: name, kind: directIndex}}
+		if dir = strings.TrimSuffix(dir, "/"); ctx.CleanURLs && dir != "" {
+			result = append(result, directCandidate{path: dir + ".html", kind: directPage})
+		}
+		return result
+	}
+	result := make([]directCandidate, 0, 3)
+	switch {
+	case !ctx.CleanURLs:
+		result = append(result, directCandidate{path: name, kind: directFile})
+	case pathpkg.Base(name) == "index.html":
+		result = append(result, directCandidate{path: name, kind: directIndex})
+	case strings.HasSuffix(name, ".html"):
+		result = append(result, directCandidate{path: name, kind: directPage})
+	default:
+		result = append(result, directCandidate{path: name, kind: directFile})
+		if pathpkg.Ext(name) == "" {
+			result = append(result, directCandidate{path: name + ".html", kind: directPage})
+		}
+	}
+	if name != "" && !strings.HasSuffix(name, "index.html") {
+		result = append(result, directCandidate{path: name + "/index.html", kind: directIndex})
+	}
+	return result
+}
+
+// directCanonicalPath 按 clean_urls 与 trailing_slash 策略返回规范的请求路径
+func directCanonicalPath(ctx core.FilterContext, urlPath string, kind int) string {
+	if ctx.Path == "index.html" {
+		// 页面根路径保持不变
+		return urlPath
+	}
+	switch kind {
+	case directIndex:
+		stem := strings.TrimSuffix(strings.TrimSuffix(urlPath, "index.html"), "/")
+		if ctx.TrailingSlash == core.TrailingSlashNever {
+			return stem
+		}
+		return stem + "/"
+	case directPage:
+		stem := strings.TrimSuffix(strings.TrimSuffix(urlPath, "/"), ".html")
+		if ctx.TrailingSlash == core.TrailingSlashAlways {
+			return stem + "/"
+		}
+		return stem
+	}
+	return urlPath
+}
@@ -48,9 +48,10 @@ func installHostGlobals(
 	}
 
 	meta, err := newFrozenObject(vm, map[string]any{
-		"org":    ctx.Owner,
-		"repo":   ctx.Repo,
-		"commit": ctx.CommitID,
+		"org":       ctx.Owner,
+		"repo":      ctx.Repo,
+		"commit":    ctx.CommitID,
+		"subdomain": ctx.Subdomain,
 	})
 	if err != nil {
 		return nil, err
@@ -58,6 +59,32 @@ func installHostGlobals(
 	auth, err := newFrozenObject(vm, map[string]any{
 		"authenticated": ctx.Auth.Authenticated,
 		"identity":      authIdentityMap(ctx.Auth.Identity),
+		// share(path, { ttl: 秒, ip }) 以当前登录用户身份为仓库内路径签发分享链接
+		"share": func(target string, options ...goja.Value) (goja.Value, error) {
+			if ctx.Share == nil {
+				return nil, errors.New("share is not supported in this context")
+			}
+			var ttl time.Duration
+			var ip string
+			if len(options) > 0 && !isNilish(options[0]) {
+				obj, ok := valueObject(vm, options[0])
+				if !ok {
+					return nil, errors.New("invalid share options")
+				}
+				if value, ok := objectInt64(obj, "ttl"); ok {
+					ttl = time.Duration(value) * time.Second
+				}
+				ip, _ = objectString(obj, "ip")
+			}
+			link, expireAt, err := ctx.Share(target, ttl, ip)
+			if err != nil {
+				return nil, err
+			}
+			return vm.ToValue(map[string]any{
+				"url":      link,
+				"expireAt": expireAt.UTC().Format(time.RFC3339),
+			}), nil
+		},
 	})
 	if err != nil {
 		return nil, err
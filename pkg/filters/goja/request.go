@@ -174,7 +174,7 @@ func requestStateFromValue(vm *goja.Runtime, value goja.Value) (*webRequestState
 	return state, ok
 }
 
-func newIncomingRequestObject(vm *goja.Runtime, loop *eventloop.EventLoop, runtime *runtimeState, req *http.Request, maxBodyBytes int64, closers *Closers) (*goja.Object, error) {
+func newIncomingRequestObject(vm *goja.Runtime, loop *eventloop.EventLoop, runtime *runtimeState, req *http.Request, routeParams core.RouteParams, maxBodyBytes int64, closers *Closers) (*goja.Object, error) {
 	info := core.RequestInfoFromRequest(req)
 	signal, abort := newAbortSignal(vm)
 	var source bodySource
@@ -211,6 +211,17 @@ func newIncomingRequestObject(vm *goja.Runtime, loop *eventloop.EventLoop, runti
 		signal:   signal,
 		abort:    abort,
 	})
+	params := make(map[string]any, len(routeParams))
+	for name, value := range routeParams {
+		params[name] = value
+	}
+	paramsObj, err := newFrozenObject(vm, params)
+	if err != nil {
+		return nil, err
+	}
+	if err = requestObj.Set("params", paramsObj); err != nil {
+		return nil, err
+	}
 	return requestObj, nil
 }
 
@@ -67,5 +67,5 @@ func initRuntime(
 		return nil, err
 	}
 	closers.AddCloser(closer.Close)
-	return newIncomingRequestObject(vm, jsLoop, runtime, request, maxRequestBodyBytes, closers)
+	return newIncomingRequestObject(vm, jsLoop, runtime, request, ctx.RouteParams, maxRequestBodyBytes, closers)
 }
@@ -0,0 +1,69 @@
+package filters
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+// FilterInstHeaders 为命中的路径追加自定义响应头，参数为响应头名称到值或值列表的映射
+func FilterInstHeaders(_ core.GlobalFilterInit) (core.FilterInstance, error) {
+	return func(config core.Params) (core.FilterCall, error) {
+		headers := make(http.Header, len(config))
+		for name, value := range config {
+			var values []string
+			switch item := value.(type) {
+			case string:
+				values = []string{item}
+			case []string:
+				values = item
+			case []any:
+				for _, v := range item {
+					s, ok := v.(string)
+					if !ok {
+						return nil, errors.Errorf("header %q values must be strings", name)
+					}
+					values = append(values, s)
+				}
+			default:
+				return nil, errors.Errorf("header %q must be a string or a list of strings", name)
+			}
+			for _, v := range values {
+				if err := core.ValidateResponseHeader(name, v); err != nil {
+					return nil, err
+				}
+				headers.Add(name, v)
+			}
+		}
+		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
+			target := ctx.ResponseHeaders
+			if target == nil {
+				target = writer.Header()
+			}
+			for name, values := range headers {
+				if name == "Cache-Control" {
+					values = privateCacheControl(ctx, values)
+				}
+				target[name] = values
+			}
+			return next(ctx, writer, request)
+		}, nil
+	}, nil
+}
+
+// privateCacheControl 私有或已登录的响应不允许被共享缓存
+func privateCacheControl(ctx core.FilterContext, values []string) []string {
+	if !ctx.Private && !ctx.Protected && !ctx.Auth.Authenticated {
+		return values
+	}
+	result := make([]string, len(values))
+	for i, value := range values {
+		if strings.HasPrefix(strings.ToLower(value), "public") {
+			value = "private" + value[len("public"):]
+		}
+		result[i] = value
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+func TestHeadersPrivateCacheControl(t *testing.T) {
+	instance, err := FilterInstHeaders(core.GlobalFilterInit{})
+	require.NoError(t, err)
+	call, err := instance(core.Params{
+		"Cache-Control": "public, max-age=600",
+		"Link":          []any{"</a.css>; rel=preload", "</b.js>; rel=preload"},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	headers := make(http.Header)
+	err = call(core.FilterContext{
+		Context: req.Context(),
+		PageContent: &core.PageContent{
+			PageMetaContent: &core.PageMetaContent{Private: true},
+		},
+		ResponseHeaders: headers,
+	}, httptest.NewRecorder(), req, func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request) error {
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "private, max-age=600", headers.Get("Cache-Control"))
+	assert.Len(t, headers.Values("Link"), 2)
+
+	_, err = instance(core.Params{"X-Test": 1})
+	assert.Error(t, err)
+}
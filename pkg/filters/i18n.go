@@ -0,0 +1,155 @@
+package filters
+
+import (
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+var regexpLocale = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+const (
+	i18nModeRedirect = "redirect"
+	i18nModeRewrite  = "rewrite"
+)
+
+// FilterInstI18n 按 Accept-Language 与记住的 Cookie 选择语言目录，缺失的翻译回退到默认语言
+func FilterInstI18n(_ core.GlobalFilterInit) (core.FilterInstance, error) {
+	return func(config core.Params) (core.FilterCall, error) {
+		var param struct {
+			Locales []string `json:"locales"`
+			Default string   `json:"default"`
+			Mode    string   `json:"mode"`
+			Cookie  string   `json:"cookie"`
+		}
+		if err := config.Unmarshal(&param); err != nil {
+			return nil, err
+		}
+		if len(param.Locales) == 0 {
+			return nil, errors.New("filter i18n: locales is empty")
+		}
+		for _, locale := range param.Locales {
+			if !regexpLocale.MatchString(locale) {
+				return nil, errors.Errorf("filter i18n: invalid locale %q", locale)
+			}
+		}
+		if param.Default == "" {
+			param.Default = param.Locales[0]
+		}
+		if !slices.Contains(param.Locales, param.Default) {
+			return nil, errors.Errorf("filter i18n: default locale %q is not in locales", param.Default)
+		}
+		switch param.Mode {
+		case "":
+			param.Mode = i18nModeRedirect
+		case i18nModeRedirect, i18nModeRewrite:
+		default:
+			return nil, errors.Errorf("filter i18n: invalid mode %q", param.Mode)
+		}
+		if param.Cookie == "" {
+			param.Cookie = "lang"
+		}
+		if err := (&http.Cookie{Name: param.Cookie, Value: "x"}).Valid(); err != nil {
+			return nil, errors.Wrapf(err, "filter i18n: invalid cookie name %q", param.Cookie)
+		}
+		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
+			locale, rest, ok := strings.Cut(ctx.Path, "/")
+			if ok && slices.Contains(param.Locales, locale) {
+				// 仅记住地址中显式访问的语言，内部改写与回退不影响
+				explicit := strings.HasPrefix(strings.TrimPrefix(request.URL.Path, ctx.BasePath+"/"), locale+"/")
+				if cookie, err := request.Cookie(param.Cookie); explicit && (err != nil || cookie.Value != locale) {
+					http.SetCookie(writer, &http.Cookie{
+						Name:     param.Cookie,
+						Value:    locale,
+						Path:     ctx.BasePath + "/",
+						MaxAge:   365 * 24 * 60 * 60,
+						Secure:   core.RequestInfoFromRequest(request).Scheme == "https",
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+				err := next(ctx, writer, request)
+				if locale == param.Default || !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				// 翻译缺失时回退到默认语言
+				return rewrite(ctx, writer, request, param.Default+"/"+rest)
+			}
+			if ctx.Path != "index.html" {
+				// 语言目录外的文件原样提供，不存在时再选择语言
+				err := next(ctx, writer, request)
+				if !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+			}
+			locale = param.Default
+			if cookie, err := request.Cookie(param.Cookie); err == nil && slices.Contains(param.Locales, cookie.Value) {
+				locale = cookie.Value
+			} else if negotiated, ok := negotiateLocale(request.Header.Get("Accept-Language"), param.Locales); ok {
+				locale = negotiated
+			}
+			writer.Header().Add("Vary", "Accept-Language, Cookie")
+			path := trimIndex(ctx.Path)
+			if param.Mode == i18nModeRewrite {
+				return rewrite(ctx, writer, request, locale+"/"+path)
+			}
+			target := ctx.BasePath + "/" + locale + "/" + path
+			if request.URL.RawQuery != "" {
+				target += "?" + request.URL.RawQuery
+			}
+			http.Redirect(writer, request, target, http.StatusFound)
+			return nil
+		}, nil
+	}, nil
+}
+
+// negotiateLocale 按 Accept-Language 的权重选择支持的语言，主语言相同视为匹配
+func negotiateLocale(header string, locales []string) (string, bool) {
+	type languageRange struct {
+		tag string
+		q   float64
+	}
+	ranges := make([]languageRange, 0)
+	for _, item := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			ranges = append(ranges, languageRange{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	for _, item := range ranges {
+		for _, locale := range locales {
+			if strings.EqualFold(item.tag, locale) {
+				return locale, true
+			}
+		}
+		primary, _, _ := strings.Cut(item.tag, "-")
+		for _, locale := range locales {
+			localePrimary, _, _ := strings.Cut(locale, "-")
+			if strings.EqualFold(primary, localePrimary) {
+				return locale, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,29 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	locales := []string{"en", "zh-CN", "ja"}
+	tests := []struct {
+		header   string
+		expected string
+		ok       bool
+	}{
+		{header: "ja", expected: "ja", ok: true},
+		{header: "zh-cn,zh;q=0.9,en;q=0.8", expected: "zh-CN", ok: true},
+		{header: "zh-TW;q=0.9,en;q=0.5", expected: "zh-CN", ok: true},
+		{header: "fr;q=1,en;q=0.2", expected: "en", ok: true},
+		{header: "en;q=0.1, ja;q=0.8", expected: "ja", ok: true},
+		{header: "en;q=0, fr", ok: false},
+		{header: "", ok: false},
+	}
+	for _, tt := range tests {
+		locale, ok := negotiateLocale(tt.header, locales)
+		assert.Equal(t, tt.ok, ok, tt.header)
+		assert.Equal(t, tt.expected, locale, tt.header)
+	}
+}
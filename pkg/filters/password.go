@@ -0,0 +1,31 @@
+package filters
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+// FilterInstPassword 命中的路径要求访问口令，参数与页面 password 相同
+func FilterInstPassword(_ core.GlobalFilterInit) (core.FilterInstance, error) {
+	return func(config core.Params) (core.FilterCall, error) {
+		var password core.PagePassword
+		if err := config.Unmarshal(&password); err != nil {
+			return nil, err
+		}
+		if err := password.Normalize(); err != nil {
+			return nil, err
+		}
+		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
+			if ctx.RequirePassword == nil {
+				return errors.New("password is not supported in this context")
+			}
+			if !ctx.RequirePassword(writer, request, &password) {
+				return nil
+			}
+			ctx.Protected = true
+			return next(ctx, writer, request)
+		}, nil
+	}, nil
+}
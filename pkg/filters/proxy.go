@@ -68,9 +68,19 @@ func FilterInstProxy(init core.GlobalFilterInit) (core.FilterInstance, error) {
 			return nil, err
 		}
 
+		// 目标路径引用路由参数时，以展开后的路径替换请求路径
+		routePath := targetURL.Path
+		targetParams := core.HasRouteParams(routePath)
 		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
 			proxyPath := "/" + ctx.Path
+			targetURL := targetURL
 			targetPath := strings.TrimPrefix(proxyPath, param.Prefix)
+			if targetParams {
+				expanded := *targetURL
+				expanded.Path, expanded.RawPath = "", ""
+				targetURL = &expanded
+				targetPath = ctx.RouteParams.Expand(routePath)
+			}
 			if !strings.HasPrefix(targetPath, "/") {
 				targetPath = "/" + targetPath
 			}
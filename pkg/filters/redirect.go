@@ -18,13 +18,15 @@ var portExp = regexp.MustCompile(`:\d+$`)
 func FilterInstRedirect(_ core.GlobalFilterInit) (core.FilterInstance, error) {
 	return func(config core.Params) (core.FilterCall, error) {
 		var param struct {
-			Targets []string `json:"targets"`
-			Code    int      `json:"code"`
+			Targets   []string `json:"targets"`
+			To        string   `json:"to"` // 路由级跳转目标 host/path 或 /path，无论访问域名均跳转
+			Code      int      `json:"code"`
+			Canonical bool     `json:"canonical"` // 除通配符别名外，所有非首个目标的域名都跳转
 		}
 		if err := config.Unmarshal(&param); err != nil {
 			return nil, err
 		}
-		if len(param.Targets) == 0 {
+		if len(param.Targets) == 0 && param.To == "" {
 			return nil, errors.New("no targets")
 		}
 		if param.Code == 0 {
@@ -33,29 +35,53 @@ func FilterInstRedirect(_ core.GlobalFilterInit) (core.FilterInstance, error) {
 		if param.Code < 300 || param.Code > 399 {
 			return nil, fmt.Errorf("invalid code: %d", param.Code)
 		}
+		if param.To != "" {
+			toHost, toPath, _ := strings.Cut(param.To, "/")
+			return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, _ core.NextCall) error {
+				host := toHost
+				if host == "" {
+					host = request.Host
+				}
+				slog.Debug("redirect", "src", request.Host, "dst", param.To)
+				redirectTo(writer, request, host, ctx.RouteParams.Expand(toPath), param.Code)
+				return nil
+			}, nil
+		}
 		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
 			domain := portExp.ReplaceAllString(strings.ToLower(request.Host), "")
-			if len(param.Targets) > 0 && !slices.Contains(ctx.Alias, domain) {
+			matched := core.MatchAliasDomain(ctx.Alias, domain)
+			if param.Canonical {
+				matched = domain == param.Targets[0] || (matched && !slices.Contains(ctx.Alias, domain))
+			}
+			if !matched {
 				// 重定向到配置的地址
 				slog.Debug("redirect", "src", request.Host, "dst", param.Targets[0])
 				path := ctx.Path
 				if strings.HasSuffix(path, "/index.html") || path == "index.html" {
 					path = strings.TrimSuffix(path, "index.html")
+					if ctx.TrailingSlash == core.TrailingSlashNever {
+						// 与 direct 的规范路径保持一致，避免二次跳转
+						path = strings.TrimSuffix(path, "/")
+					}
 				}
-				scheme := core.RequestInfoFromRequest(request).Scheme
-				if scheme == "" {
-					scheme = "http"
-				}
-				target := &url.URL{
-					Scheme:   scheme,
-					Host:     param.Targets[0],
-					Path:     "/" + path,
-					RawQuery: request.URL.RawQuery,
-				}
-				http.Redirect(writer, request, target.String(), param.Code)
+				redirectTo(writer, request, param.Targets[0], path, param.Code)
 				return nil
 			}
 			return next(ctx, writer, request)
 		}, nil
 	}, nil
 }
+
+func redirectTo(writer http.ResponseWriter, request *http.Request, host, path string, code int) {
+	scheme := core.RequestInfoFromRequest(request).Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	target := &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     "/" + path,
+		RawQuery: request.URL.RawQuery,
+	}
+	http.Redirect(writer, request, target.String(), code)
+}
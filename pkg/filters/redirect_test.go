@@ -47,3 +47,32 @@ func TestRedirectUsesRequestInfoScheme(t *testing.T) {
 	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
 	assert.Equal(t, "https://target.example.com/docs/?q=1", rec.Header().Get("Location"))
 }
+
+func TestRedirectTargetsWithPathKeepHostSemantics(t *testing.T) {
+	instance, err := FilterInstRedirect(core.GlobalFilterInit{})
+	require.NoError(t, err)
+	call, err := instance(core.Params{"targets": []string{"target.example.com/base"}})
+	require.NoError(t, err)
+	filterCtx := core.FilterContext{
+		PageContent: &core.PageContent{
+			PageMetaContent: &core.PageMetaContent{Alias: []string{"target.example.com"}},
+			Path:            "docs/page.html",
+		},
+	}
+
+	// 已在别名域名上访问时不跳转
+	req := httptest.NewRequest(http.MethodGet, "https://target.example.com/docs/page.html", nil)
+	nextCalled := false
+	err = call(filterCtx, httptest.NewRecorder(), req, func(core.FilterContext, http.ResponseWriter, *http.Request) error {
+		nextCalled = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, nextCalled)
+
+	req = httptest.NewRequest(http.MethodGet, "https://source.example.com/docs/page.html", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, call(filterCtx, rec, req, nil))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Contains(t, rec.Header().Get("Location"), "docs/page.html")
+}
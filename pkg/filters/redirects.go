@@ -0,0 +1,85 @@
+package filters
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+// FilterInstRedirects 处理 _redirects 文件中的规则，规则由 ServerMeta 解析后传入
+func FilterInstRedirects(_ core.GlobalFilterInit) (core.FilterInstance, error) {
+	return func(config core.Params) (core.FilterCall, error) {
+		var param struct {
+			Rules []core.RedirectRule `json:"rules"`
+		}
+		if err := config.Unmarshal(&param); err != nil {
+			return nil, err
+		}
+		table, err := core.NewRedirectTable(param.Rules)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
+			path := strings.Trim(trimIndex(ctx.Path), "/")
+			host := portExp.ReplaceAllString(strings.ToLower(request.Host), "")
+			rule, params, ok := table.Match(host, path, request.URL.Query())
+			if !ok {
+				return next(ctx, writer, request)
+			}
+			if !rule.Force {
+				// 未强制的规则不覆盖已存在的文件
+				err := next(ctx, writer, request)
+				if (err != nil && !errors.Is(err, os.ErrNotExist)) || err == nil {
+					return err
+				}
+			}
+			target := params.Expand(rule.To)
+			switch rule.Status {
+			case http.StatusOK:
+				target, _, _ = strings.Cut(target, "?")
+				return rewrite(ctx, writer, request, target)
+			case http.StatusNotFound:
+				return serveRedirectsNotFound(ctx, writer, redirectsFilePath(target))
+			}
+			if strings.HasPrefix(target, "/") {
+				// 合并展开后的前导斜杠，避免 //host 被视为协议相对地址
+				target = ctx.BasePath + "/" + strings.TrimLeft(target, "/\\")
+			}
+			if len(rule.Query) == 0 && request.URL.RawQuery != "" && !strings.Contains(target, "?") {
+				target += "?" + request.URL.RawQuery
+			}
+			http.Redirect(writer, request, target, rule.Status)
+			return nil
+		}, nil
+	}, nil
+}
+
+// redirectsFilePath 将改写目标转换为仓库内的文件路径
+func redirectsFilePath(target string) string {
+	target, _, _ = strings.Cut(target, "?")
+	target = strings.TrimPrefix(target, "/")
+	if target == "" || strings.HasSuffix(target, "/") {
+		target += "index.html"
+	}
+	return target
+}
+
+func serveRedirectsNotFound(ctx core.FilterContext, writer http.ResponseWriter, path string) error {
+	resp, err := ctx.NativeOpen(ctx, path, nil)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+	writer.Header().Set("Content-Type", mime.TypeByExtension(filepath.Ext(path)))
+	writer.WriteHeader(http.StatusNotFound)
+	_, err = io.Copy(writer, resp.Body)
+	return err
+}
@@ -0,0 +1,33 @@
+package filters
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+// FilterInstRewrite 将请求改写到同一提交内的其他路径并重新匹配路由，地址栏不变
+func FilterInstRewrite(_ core.GlobalFilterInit) (core.FilterInstance, error) {
+	return func(config core.Params) (core.FilterCall, error) {
+		var param struct {
+			Target string `json:"target"`
+		}
+		if err := config.Unmarshal(&param); err != nil {
+			return nil, err
+		}
+		if param.Target == "" {
+			return nil, errors.New("filter rewrite: target is empty")
+		}
+		return func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, next core.NextCall) error {
+			return rewrite(ctx, writer, request, ctx.RouteParams.Expand(param.Target))
+		}, nil
+	}, nil
+}
+
+func rewrite(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, path string) error {
+	if ctx.Rewrite == nil {
+		return errors.New("rewrite is not supported in this context")
+	}
+	return ctx.Rewrite(ctx, writer, request, path)
+}
@@ -42,9 +42,10 @@ func FilterInstTemplate(_ core.GlobalFilterInit) (core.FilterInstance, error) {
 			}
 			err = parse.Execute(out, utils.NewTemplateInject(request, map[string]any{
 				"Meta": map[string]string{
-					"Org":    ctx.Owner,
-					"Repo":   ctx.Repo,
-					"Commit": ctx.CommitID,
+					"Org":       ctx.Owner,
+					"Repo":      ctx.Repo,
+					"Commit":    ctx.CommitID,
+					"Subdomain": ctx.Subdomain,
 				},
 			}, core.RequestInfoFromRequest(request).ClientIP))
 			if err != nil {
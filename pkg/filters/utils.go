@@ -15,10 +15,7 @@ func applyCacheControl(header http.Header, ctx core.FilterContext, cacheControl
 	if cacheControl == "" || header.Get("Cache-Control") != "" {
 		return
 	}
-	if (ctx.Private || ctx.Auth.Authenticated) && strings.HasPrefix(strings.ToLower(cacheControl), "public") {
-		cacheControl = "private" + cacheControl[len("public"):]
-	}
-	header.Set("Cache-Control", cacheControl)
+	header["Cache-Control"] = privateCacheControl(ctx, []string{cacheControl})
 }
 
 func writeStaticFileResponse(
@@ -41,3 +38,14 @@ func writeStaticFileResponse(
 	_, err = io.Copy(writer, resp.Body)
 	return err
 }
+
+// trimIndex 去除路径末尾的 index.html 文件名，保留目录部分的结尾 /
+func trimIndex(path string) string {
+	if path == "index.html" {
+		return ""
+	}
+	if dir, ok := strings.CutSuffix(path, "/index.html"); ok {
+		return dir + "/"
+	}
+	return path
+}
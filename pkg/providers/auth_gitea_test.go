@@ -77,3 +77,121 @@ func TestGiteaCallbackAndAuthorizeRepo(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, allowed)
 }
+
+func TestGiteaAuthorizeRepoWrite(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/org/writable":
+			_ = json.NewEncoder(w).Encode(map[string]any{"permissions": map[string]bool{"pull": true, "push": true}})
+		case "/api/v1/repos/org/readonly":
+			_ = json.NewEncoder(w).Encode(map[string]any{"permissions": map[string]bool{"pull": true}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	provider, err := NewGitea(ts.Client(), GiteaConfig{
+		Server:            ts.URL,
+		ClientID:          "cid",
+		ClientSecret:      "secret",
+		RedirectURL:       ts.URL + "/callback",
+		AllowInsecureHTTP: true,
+	})
+	require.NoError(t, err)
+	session := &core.AuthSession{Private: json.RawMessage(`{"access_token":"token-1"}`)}
+
+	for repo, expected := range map[string]bool{"writable": true, "readonly": false, "missing": false} {
+		allowed, err := provider.AuthorizeRepoWrite(context.Background(), session, "org", repo)
+		require.NoError(t, err)
+		assert.Equal(t, expected, allowed, repo)
+	}
+	allowed, err := provider.AuthorizeRepo(context.Background(), session, "org", "readonly")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestGiteaAccessMembership(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token token-1", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v1/user/orgs":
+			if r.URL.Query().Get("page") != "1" {
+				_ = json.NewEncoder(w).Encode([]any{})
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"username": "docs"}})
+		case "/api/v1/user/teams":
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"name": "writers", "organization": map[string]any{"username": "docs"}},
+			})
+		case "/api/v1/repos/docs/admin":
+			_ = json.NewEncoder(w).Encode(map[string]any{"permissions": map[string]bool{"pull": true, "push": true, "admin": true}})
+		case "/api/v1/repos/docs/handbook":
+			_ = json.NewEncoder(w).Encode(map[string]any{"permissions": map[string]bool{"pull": true}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	provider, err := NewGitea(ts.Client(), GiteaConfig{
+		Server:            ts.URL,
+		ClientID:          "cid",
+		ClientSecret:      "secret",
+		RedirectURL:       ts.URL + "/callback",
+		AllowInsecureHTTP: true,
+	})
+	require.NoError(t, err)
+	session := &core.AuthSession{Private: json.RawMessage(`{"access_token":"token-1"}`)}
+	ctx := context.Background()
+
+	member, err := provider.IsOrgMember(ctx, session, "Docs")
+	require.NoError(t, err)
+	assert.True(t, member)
+	member, err = provider.IsOrgMember(ctx, session, "other")
+	require.NoError(t, err)
+	assert.False(t, member)
+
+	member, err = provider.IsTeamMember(ctx, session, "docs", "writers")
+	require.NoError(t, err)
+	assert.True(t, member)
+	member, err = provider.IsTeamMember(ctx, session, "other", "writers")
+	require.NoError(t, err)
+	assert.False(t, member)
+
+	for repo, expected := range map[string]string{"admin": core.PermissionAdmin, "handbook": core.PermissionRead, "missing": core.PermissionNone} {
+		permission, err := provider.RepoPermission(ctx, session, "docs", repo)
+		require.NoError(t, err)
+		assert.Equal(t, expected, permission, repo)
+	}
+}
+
+func TestGiteaAuthenticateToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/user" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "token pat-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 42, "login": "dragon"})
+	}))
+	defer ts.Close()
+
+	provider, err := NewGitea(ts.Client(), GiteaConfig{Server: ts.URL})
+	require.NoError(t, err)
+
+	session, ok, err := provider.AuthenticateToken(context.Background(), "pat-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "42", session.Identity.Subject)
+	assert.Equal(t, "dragon", session.Identity.Name)
+	assert.JSONEq(t, `{"access_token":"pat-1"}`, string(session.Private))
+
+	_, ok, err = provider.AuthenticateToken(context.Background(), "bad")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,322 @@
+package providers
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+// oidcStandIn 进程内的最小 OIDC 服务端，校验 PKCE 并签发 ID Token
+type oidcStandIn struct {
+	*httptest.Server
+	t      *testing.T
+	alg    string
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	// forgeKey 非空时以未公布的密钥签名
+	forgeKey *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]url.Values
+	claims map[string]any
+}
+
+func newOIDCStandIn(t *testing.T, alg string) *oidcStandIn {
+	s := &oidcStandIn{t: t, alg: alg, codes: map[string]url.Values{}, claims: map[string]any{}}
+	var err error
+	s.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	s.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *oidcStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 s.URL,
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"jwks_uri":               s.URL + "/jwks",
+		})
+	case "/jwks":
+		ecPub, err := s.ecKey.PublicKey.Bytes()
+		require.NoError(s.t, err)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{
+			{
+				"kty": "RSA", "kid": rsaKid(s.rsaKey), "use": "sig", "alg": "RS256",
+				"n": b64(s.rsaKey.N.Bytes()),
+				"e": b64(big.NewInt(int64(s.rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec-1", "use": "sig", "alg": "ES256", "crv": "P-256",
+				"x": b64(ecPub[1:33]), "y": b64(ecPub[33:]),
+			},
+		}})
+	case "/token":
+		require.NoError(s.t, r.ParseForm())
+		clientID, secret, _ := r.BasicAuth()
+		assert.Equal(s.t, "pages", clientID)
+		assert.Equal(s.t, "secret", secret)
+		s.mu.Lock()
+		login, ok := s.codes[r.PostForm.Get("code")]
+		s.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || b64(sum[:]) != login.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		claims := map[string]any{
+			"iss":   s.URL,
+			"aud":   "pages",
+			"sub":   "u-42",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": login.Get("nonce"),
+		}
+		s.mu.Lock()
+		for k, v := range s.claims {
+			claims[k] = v
+		}
+		s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "id_token": s.sign(claims)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *oidcStandIn) sign(claims map[string]any) string {
+	kid := rsaKid(s.rsaKey)
+	if s.alg == "ES256" {
+		kid = "ec-1"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": s.alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	if s.alg == "ES256" {
+		r, sv, err := ecdsa.Sign(rand.Reader, s.ecKey, digest[:])
+		require.NoError(s.t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), sv.FillBytes(make([]byte, 32))...)
+	} else {
+		key := s.rsaKey
+		if s.forgeKey != nil {
+			key = s.forgeKey
+		}
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(s.t, err)
+	}
+	return signed + "." + b64(signature)
+}
+
+// authorize 模拟用户在 OIDC 登录页完成登录，返回回调请求
+func (s *oidcStandIn) authorize(loginURL string) *http.Request {
+	parsed, err := url.Parse(loginURL)
+	require.NoError(s.t, err)
+	query := parsed.Query()
+	code := "code-" + query.Get("state")
+	s.mu.Lock()
+	s.codes[code] = query
+	s.mu.Unlock()
+	return httptest.NewRequest(http.MethodGet, "https://pages.example.com/.pages/auth/callback?code="+code+"&state="+url.QueryEscape(query.Get("state")), nil)
+}
+
+// rsaKid 按公钥派生 kid，轮换后的密钥使用新的 kid
+func rsaKid(key *rsa.PrivateKey) string {
+	return "rsa-" + b64(key.N.Bytes()[:6])
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func newTestOIDC(t *testing.T, server *oidcStandIn, allowedGroups ...string) *ProviderOIDC {
+	provider, err := NewOIDC(server.Client(), OIDCConfig{
+		Issuer:            server.URL,
+		ClientID:          "pages",
+		ClientSecret:      "secret",
+		RedirectURL:       "https://pages.example.com/.pages/auth/callback",
+		AllowedGroups:     allowedGroups,
+		AllowInsecureHTTP: true,
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestOIDCLoginWithPKCE(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256"} {
+		t.Run(alg, func(t *testing.T) {
+			server := newOIDCStandIn(t, alg)
+			defer server.Close()
+			server.claims["preferred_username"] = "dragon"
+			server.claims["groups"] = []string{"/docs/handbook", "engineering"}
+			provider := newTestOIDC(t, server, "engineering")
+
+			loginURL, err := provider.LoginURL(context.Background(), "state-1")
+			require.NoError(t, err)
+			query, _ := url.ParseQuery(loginURL[len(server.URL+"/authorize?"):])
+			assert.Equal(t, "S256", query.Get("code_challenge_method"))
+			assert.Equal(t, "openid profile email", query.Get("scope"))
+			assert.NotEmpty(t, query.Get("nonce"))
+
+			sess, err := provider.HandleCallback(context.Background(), server.authorize(loginURL))
+			require.NoError(t, err)
+			assert.Equal(t, "u-42", sess.Identity.Subject)
+			// 默认以 sub 作为用户名，preferred_username 可能被冒用
+			assert.Equal(t, "u-42", sess.Identity.Name)
+
+			allowed, err := provider.AuthorizeRepo(context.Background(), sess, "org", "repo")
+			require.NoError(t, err)
+			assert.True(t, allowed)
+			member, err := provider.IsTeamMember(context.Background(), sess, "Docs", "handbook")
+			require.NoError(t, err)
+			assert.True(t, member)
+			member, err = provider.IsOrgMember(context.Background(), sess, "docs")
+			require.NoError(t, err)
+			assert.True(t, member)
+			member, err = provider.IsOrgMember(context.Background(), sess, "ops")
+			require.NoError(t, err)
+			assert.False(t, member)
+		})
+	}
+}
+
+func TestOIDCAllowedGroups(t *testing.T) {
+	server := newOIDCStandIn(t, "RS256")
+	defer server.Close()
+	provider := newTestOIDC(t, server, "admins")
+
+	loginURL, err := provider.LoginURL(context.Background(), "state-1")
+	require.NoError(t, err)
+	sess, err := provider.HandleCallback(context.Background(), server.authorize(loginURL))
+	require.NoError(t, err)
+
+	allowed, err := provider.AuthorizeRepo(context.Background(), sess, "org", "repo")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	// 未配置 allowed_groups 时默认拒绝
+	allowed, err = newTestOIDC(t, server).AuthorizeRepo(context.Background(), sess, "org", "repo")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	permission, err := provider.RepoPermission(context.Background(), sess, "org", "repo")
+	require.NoError(t, err)
+	assert.Equal(t, core.PermissionNone, permission)
+}
+
+func TestOIDCUsernameClaim(t *testing.T) {
+	server := newOIDCStandIn(t, "RS256")
+	defer server.Close()
+	server.claims["email"] = "dragon@example.com"
+	provider, err := NewOIDC(server.Client(), OIDCConfig{
+		Issuer:            server.URL,
+		ClientID:          "pages",
+		ClientSecret:      "secret",
+		RedirectURL:       "https://pages.example.com/.pages/auth/callback",
+		UsernameClaim:     "email",
+		AllowInsecureHTTP: true,
+	})
+	require.NoError(t, err)
+
+	loginURL, err := provider.LoginURL(context.Background(), "state-1")
+	require.NoError(t, err)
+	sess, err := provider.HandleCallback(context.Background(), server.authorize(loginURL))
+	require.NoError(t, err)
+	assert.Equal(t, "dragon@example.com", sess.Identity.Name)
+
+	// 缺少用户名声明时回退到 sub
+	delete(server.claims, "email")
+	loginURL, err = provider.LoginURL(context.Background(), "state-2")
+	require.NoError(t, err)
+	sess, err = provider.HandleCallback(context.Background(), server.authorize(loginURL))
+	require.NoError(t, err)
+	assert.Equal(t, "u-42", sess.Identity.Name)
+}
+
+func TestOIDCRejectsInvalidIDToken(t *testing.T) {
+	server := newOIDCStandIn(t, "RS256")
+	defer server.Close()
+	provider := newTestOIDC(t, server)
+
+	// state 不同导致 code_verifier 不匹配
+	loginURL, err := provider.LoginURL(context.Background(), "state-1")
+	require.NoError(t, err)
+	req := server.authorize(loginURL)
+	req.URL.RawQuery = url.Values{"code": {req.URL.Query().Get("code")}, "state": {"state-2"}}.Encode()
+	_, err = provider.HandleCallback(context.Background(), req)
+	require.Error(t, err)
+
+	cases := map[string]map[string]any{
+		"audience": {"aud": "other"},
+		"expired":  {"exp": time.Now().Add(-time.Hour).Unix()},
+		"nonce":    {"nonce": "forged"},
+		"issuer":   {"iss": "https://evil.example.com"},
+	}
+	for name, claims := range cases {
+		t.Run(name, func(t *testing.T) {
+			server.mu.Lock()
+			server.claims = claims
+			server.mu.Unlock()
+			loginURL, err := provider.LoginURL(context.Background(), "state-"+name)
+			require.NoError(t, err)
+			_, err = provider.HandleCallback(context.Background(), server.authorize(loginURL))
+			assert.Error(t, err)
+		})
+	}
+
+	// 使用未公布的密钥签名
+	server.mu.Lock()
+	server.claims = map[string]any{}
+	server.mu.Unlock()
+	server.forgeKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	loginURL, err = provider.LoginURL(context.Background(), "state-forged")
+	require.NoError(t, err)
+	_, err = provider.HandleCallback(context.Background(), server.authorize(loginURL))
+	assert.Error(t, err)
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	server := newOIDCStandIn(t, "RS256")
+	defer server.Close()
+	provider := newTestOIDC(t, server)
+
+	loginURL, err := provider.LoginURL(context.Background(), "state-1")
+	require.NoError(t, err)
+	_, err = provider.HandleCallback(context.Background(), server.authorize(loginURL))
+	require.NoError(t, err)
+
+	// 密钥轮换后按 kid 找不到缓存的公钥时重新拉取 JWKS
+	server.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	loginURL, err = provider.LoginURL(context.Background(), "state-2")
+	require.NoError(t, err)
+	_, err = provider.HandleCallback(context.Background(), server.authorize(loginURL))
+	require.NoError(t, err)
+}
+
+func TestOIDCRegistry(t *testing.T) {
+	factory, ok := core.GetAuthProviderFactory("oidc")
+	require.True(t, ok)
+	_, err := factory(nil, json.RawMessage(`{"issuer":"https://id.example.com"}`))
+	assert.Error(t, err)
+}
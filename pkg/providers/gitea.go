@@ -41,6 +41,11 @@ type ProviderGitea struct {
 	allowInsecureHTTP bool
 }
 
+const (
+	giteaListPageSize = 50
+	giteaListMaxPages = 20
+)
+
 type giteaAuthSession struct {
 	AccessToken string `json:"access_token"`
 }
@@ -210,16 +215,38 @@ func (g *ProviderGitea) HandleCallback(ctx context.Context, req *http.Request) (
 		return nil, errors.New("missing access token")
 	}
 
+	return g.userSession(ctx, token.AccessToken)
+}
+
+// AuthenticateToken 以个人访问令牌或 OAuth 令牌查询当前用户，令牌无效时返回 false
+func (g *ProviderGitea) AuthenticateToken(ctx context.Context, token string) (*core.AuthSession, bool, error) {
+	sess, err := g.userSession(ctx, token)
+	if errors.Is(err, errGiteaUnauthorized) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return sess, true, nil
+}
+
+var errGiteaUnauthorized = errors.New("gitea token unauthorized")
+
+// userSession 查询令牌所属用户并构造会话，后续授权请求沿用该令牌
+func (g *ProviderGitea) userSession(ctx context.Context, accessToken string) (*core.AuthSession, error) {
 	userReq, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(g.BaseURL, "/")+"/api/v1/user", nil)
 	if err != nil {
 		return nil, err
 	}
-	userReq.Header.Set("Authorization", "token "+token.AccessToken)
+	userReq.Header.Set("Authorization", "token "+accessToken)
 	userResp, err := g.client.Do(userReq)
 	if err != nil {
 		return nil, err
 	}
 	defer userResp.Body.Close()
+	if userResp.StatusCode == http.StatusUnauthorized || userResp.StatusCode == http.StatusForbidden {
+		return nil, errGiteaUnauthorized
+	}
 	if userResp.StatusCode < 200 || userResp.StatusCode >= 300 {
 		return nil, errors.New("user lookup failed")
 	}
@@ -230,7 +257,7 @@ func (g *ProviderGitea) HandleCallback(ctx context.Context, req *http.Request) (
 	if err = json.NewDecoder(userResp.Body).Decode(&user); err != nil {
 		return nil, err
 	}
-	privateData, err := json.Marshal(giteaAuthSession{AccessToken: token.AccessToken})
+	privateData, err := json.Marshal(giteaAuthSession{AccessToken: accessToken})
 	if err != nil {
 		return nil, err
 	}
@@ -244,28 +271,137 @@ func (g *ProviderGitea) HandleCallback(ctx context.Context, req *http.Request) (
 }
 
 func (g *ProviderGitea) AuthorizeRepo(ctx context.Context, sess *core.AuthSession, owner, repo string) (bool, error) {
-	var private giteaAuthSession
-	if err := json.Unmarshal(sess.Private, &private); err != nil {
-		return false, err
-	}
-	repoURL := strings.TrimRight(g.BaseURL, "/") + "/api/v1/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
-	repoReq, err := http.NewRequestWithContext(ctx, http.MethodGet, repoURL, nil)
-	if err != nil {
+	_, found, err := g.repoPermissions(ctx, sess, owner, repo)
+	return found, err
+}
+
+// AuthorizeRepoWrite 拥有 push 或 admin 权限视为可写
+func (g *ProviderGitea) AuthorizeRepoWrite(ctx context.Context, sess *core.AuthSession, owner, repo string) (bool, error) {
+	permissions, found, err := g.repoPermissions(ctx, sess, owner, repo)
+	if err != nil || !found {
 		return false, err
 	}
-	repoReq.Header.Set("Authorization", "token "+private.AccessToken)
-	repoResp, err := g.client.Do(repoReq)
+	return permissions.Push || permissions.Admin, nil
+}
+
+type giteaRepoPermissions struct {
+	Admin bool `json:"admin"`
+	Push  bool `json:"push"`
+	Pull  bool `json:"pull"`
+}
+
+func (g *ProviderGitea) repoPermissions(ctx context.Context, sess *core.AuthSession, owner, repo string) (*giteaRepoPermissions, bool, error) {
+	repoResp, err := g.authGet(ctx, sess, "/api/v1/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(repo))
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	defer repoResp.Body.Close()
-	if repoResp.StatusCode == http.StatusOK {
-		return true, nil
-	}
 	if repoResp.StatusCode == http.StatusForbidden || repoResp.StatusCode == http.StatusNotFound {
-		return false, nil
+		return nil, false, nil
+	}
+	if repoResp.StatusCode != http.StatusOK {
+		return nil, false, errors.New("repo authorization failed")
+	}
+	var result struct {
+		Permissions giteaRepoPermissions `json:"permissions"`
 	}
-	return false, errors.New("repo authorization failed")
+	if err = json.NewDecoder(repoResp.Body).Decode(&result); err != nil {
+		return nil, false, err
+	}
+	return &result.Permissions, true, nil
+}
+
+// RepoPermission 返回用户在仓库上的最高权限
+func (g *ProviderGitea) RepoPermission(ctx context.Context, sess *core.AuthSession, owner, repo string) (string, error) {
+	permissions, found, err := g.repoPermissions(ctx, sess, owner, repo)
+	switch {
+	case err != nil || !found:
+		return core.PermissionNone, err
+	case permissions.Admin:
+		return core.PermissionAdmin, nil
+	case permissions.Push:
+		return core.PermissionWrite, nil
+	default:
+		return core.PermissionRead, nil
+	}
+}
+
+// IsOrgMember 通过用户所属组织列表判断，私有成员关系同样可见
+func (g *ProviderGitea) IsOrgMember(ctx context.Context, sess *core.AuthSession, org string) (bool, error) {
+	found := false
+	err := g.authList(ctx, sess, "/api/v1/user/orgs", func(raw json.RawMessage) (bool, error) {
+		var item struct {
+			Username string `json:"username"`
+		}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return false, err
+		}
+		found = strings.EqualFold(item.Username, org)
+		return found, nil
+	})
+	return found, err
+}
+
+// IsTeamMember 通过用户所属团队列表判断
+func (g *ProviderGitea) IsTeamMember(ctx context.Context, sess *core.AuthSession, org, team string) (bool, error) {
+	found := false
+	err := g.authList(ctx, sess, "/api/v1/user/teams", func(raw json.RawMessage) (bool, error) {
+		var item struct {
+			Name         string `json:"name"`
+			Organization struct {
+				Username string `json:"username"`
+			} `json:"organization"`
+		}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return false, err
+		}
+		found = strings.EqualFold(item.Organization.Username, org) && strings.EqualFold(item.Name, team)
+		return found, nil
+	})
+	return found, err
+}
+
+// authGet 以登录用户的令牌请求 Gitea API
+func (g *ProviderGitea) authGet(ctx context.Context, sess *core.AuthSession, path string) (*http.Response, error) {
+	var private giteaAuthSession
+	if err := json.Unmarshal(sess.Private, &private); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(g.BaseURL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+private.AccessToken)
+	return g.client.Do(req)
+}
+
+// authList 逐页读取列表接口，visit 返回 true 时停止
+func (g *ProviderGitea) authList(ctx context.Context, sess *core.AuthSession, path string, visit func(raw json.RawMessage) (bool, error)) error {
+	for page := 1; page <= giteaListMaxPages; page++ {
+		resp, err := g.authGet(ctx, sess, fmt.Sprintf("%s?page=%d&limit=%d", path, page, giteaListPageSize))
+		if err != nil {
+			return err
+		}
+		var items []json.RawMessage
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("gitea list %s failed: %s", path, resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&items)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if stop, err := visit(item); err != nil || stop {
+				return err
+			}
+		}
+		if len(items) < giteaListPageSize {
+			return nil
+		}
+	}
+	return nil
 }
 
 func (g *ProviderGitea) Logout(context.Context, *core.AuthSession) error {
@@ -0,0 +1,546 @@
+package providers
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+type OIDCConfig struct {
+	Issuer            string   `json:"issuer"`
+	ClientID          string   `json:"client_id"`
+	ClientSecret      string   `json:"client_secret"`
+	RedirectURL       string   `json:"redirect_url"`
+	Scopes            []string `json:"scopes"`
+	UsernameClaim     string   `json:"username_claim"`
+	GroupsClaim       string   `json:"groups_claim"`
+	AllowedGroups     []string `json:"allowed_groups"`
+	AllowInsecureHTTP bool     `json:"allow_insecure_http"`
+}
+
+// ProviderOIDC 通用 OpenID Connect 认证提供方，使用授权码 + PKCE 登录并校验 ID Token
+type ProviderOIDC struct {
+	issuer            string
+	client            *http.Client
+	clientID          string
+	clientSecret      string
+	redirectURL       string
+	scopes            []string
+	usernameClaim     string
+	groupsClaim       string
+	allowedGroups     []string
+	allowInsecureHTTP bool
+	// pkceKey 由 state 派生 code_verifier 与 nonce，多实例部署时依赖相同的 client_secret
+	pkceKey []byte
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      []oidcKey
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcKey struct {
+	id  string
+	alg string
+	key crypto.PublicKey
+}
+
+type oidcClaims struct {
+	Issuer          string       `json:"iss"`
+	Subject         string       `json:"sub"`
+	Audience        oidcAudience `json:"aud"`
+	AuthorizedParty string       `json:"azp"`
+	Expiry          float64      `json:"exp"`
+	IssuedAt        float64      `json:"iat"`
+	Nonce           string       `json:"nonce"`
+}
+
+// oidcAudience aud 可以是字符串或字符串列表
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+type oidcAuthSession struct {
+	Groups []string `json:"groups,omitempty"`
+}
+
+const oidcClockSkew = time.Minute
+
+func init() {
+	core.RegisterAuthProvider("oidc", func(httpClient *http.Client, raw json.RawMessage) (core.AuthProvider, error) {
+		var cfg OIDCConfig
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &cfg); err != nil {
+				return nil, err
+			}
+		}
+		return NewOIDC(httpClient, cfg)
+	})
+}
+
+func NewOIDC(httpClient *http.Client, cfg OIDCConfig) (*ProviderOIDC, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("oidc auth requires issuer, client_id and redirect_url")
+	}
+	if err := validateAuthURL(cfg.Issuer, cfg.AllowInsecureHTTP); err != nil {
+		return nil, fmt.Errorf("invalid oidc issuer: %w", err)
+	}
+	if err := validateAuthURL(cfg.RedirectURL, cfg.AllowInsecureHTTP); err != nil {
+		return nil, fmt.Errorf("invalid oidc redirect_url: %w", err)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	} else if !slices.Contains(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+	if cfg.UsernameClaim == "" {
+		// preferred_username 不保证唯一且常可由用户自行修改，默认以 sub 作为用户名
+		cfg.UsernameClaim = "sub"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	var pkceKey []byte
+	if cfg.ClientSecret != "" {
+		sum := sha256.Sum256([]byte("gitea-pages/oidc/" + cfg.ClientSecret))
+		pkceKey = sum[:]
+	} else {
+		pkceKey = make([]byte, 32)
+		if _, err := rand.Read(pkceKey); err != nil {
+			return nil, err
+		}
+	}
+	return &ProviderOIDC{
+		issuer:            strings.TrimRight(cfg.Issuer, "/"),
+		client:            httpClient,
+		clientID:          cfg.ClientID,
+		clientSecret:      cfg.ClientSecret,
+		redirectURL:       cfg.RedirectURL,
+		scopes:            cfg.Scopes,
+		usernameClaim:     cfg.UsernameClaim,
+		groupsClaim:       cfg.GroupsClaim,
+		allowedGroups:     normalizeOIDCGroups(cfg.AllowedGroups),
+		allowInsecureHTTP: cfg.AllowInsecureHTTP,
+		pkceKey:           pkceKey,
+	}, nil
+}
+
+func (o *ProviderOIDC) LoginURL(ctx context.Context, state string) (string, error) {
+	discovery, err := o.loadDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(o.derive("verifier", state)))
+	values := url.Values{}
+	values.Set("client_id", o.clientID)
+	values.Set("redirect_uri", o.redirectURL)
+	values.Set("response_type", "code")
+	values.Set("scope", strings.Join(o.scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", o.derive("nonce", state))
+	values.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	values.Set("code_challenge_method", "S256")
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+func (o *ProviderOIDC) HandleCallback(ctx context.Context, req *http.Request) (*core.AuthSession, error) {
+	query := req.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		return nil, fmt.Errorf("oidc login failed: %s", errCode)
+	}
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		return nil, errors.New("missing code")
+	}
+	discovery, err := o.loadDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.redirectURL)
+	form.Set("code_verifier", o.derive("verifier", state))
+	if o.clientSecret == "" {
+		form.Set("client_id", o.clientID)
+	}
+	tokenReq, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.Header.Set("Accept", "application/json")
+	if o.clientSecret != "" {
+		tokenReq.SetBasicAuth(url.QueryEscape(o.clientID), url.QueryEscape(o.clientSecret))
+	}
+	tokenResp, err := o.client.Do(tokenReq)
+	if err != nil {
+		return nil, err
+	}
+	defer tokenResp.Body.Close()
+	if tokenResp.StatusCode < 200 || tokenResp.StatusCode >= 300 {
+		return nil, errors.New("token exchange failed")
+	}
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err = json.NewDecoder(tokenResp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("missing id token")
+	}
+	claims, err := o.verifyIDToken(ctx, token.IDToken, o.derive("nonce", state))
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	name, _ := claims[o.usernameClaim].(string)
+	if name == "" {
+		name = subject
+	}
+	privateData, err := json.Marshal(oidcAuthSession{Groups: oidcClaimStrings(claims[o.groupsClaim])})
+	if err != nil {
+		return nil, err
+	}
+	return &core.AuthSession{
+		Identity: core.AuthIdentity{
+			Subject: subject,
+			Name:    name,
+		},
+		Private: privateData,
+	}, nil
+}
+
+// AuthorizeRepo 要求属于 allowed_groups 中任一分组，未配置时拒绝，页面需通过访问规则授权
+func (o *ProviderOIDC) AuthorizeRepo(_ context.Context, sess *core.AuthSession, _, _ string) (bool, error) {
+	if len(o.allowedGroups) == 0 {
+		return false, nil
+	}
+	groups, err := oidcSessionGroups(sess)
+	if err != nil {
+		return false, err
+	}
+	for _, group := range groups {
+		if slices.Contains(o.allowedGroups, group) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RepoPermission OIDC 无法获知仓库权限，按 permission 声明的访问规则不会通过
+func (o *ProviderOIDC) RepoPermission(context.Context, *core.AuthSession, string, string) (string, error) {
+	return core.PermissionNone, nil
+}
+
+// IsOrgMember 分组 org 或 org/* 视为组织成员
+func (o *ProviderOIDC) IsOrgMember(_ context.Context, sess *core.AuthSession, org string) (bool, error) {
+	groups, err := oidcSessionGroups(sess)
+	if err != nil {
+		return false, err
+	}
+	org = strings.ToLower(org)
+	for _, group := range groups {
+		if group == org || strings.HasPrefix(group, org+"/") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsTeamMember 分组 org/team 视为团队成员
+func (o *ProviderOIDC) IsTeamMember(_ context.Context, sess *core.AuthSession, org, team string) (bool, error) {
+	groups, err := oidcSessionGroups(sess)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(groups, strings.ToLower(org+"/"+team)), nil
+}
+
+func (o *ProviderOIDC) Logout(context.Context, *core.AuthSession) error {
+	return nil
+}
+
+// derive 以 HMAC 从 state 派生一次性值，回调时无需额外存储即可还原
+func (o *ProviderOIDC) derive(purpose, state string) string {
+	mac := hmac.New(sha256.New, o.pkceKey)
+	mac.Write([]byte(purpose + "\x00" + state))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (o *ProviderOIDC) loadDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+	var discovery oidcDiscovery
+	if err := o.getJSON(ctx, o.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != o.issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch: %s", discovery.Issuer)
+	}
+	for _, endpoint := range []string{discovery.AuthorizationEndpoint, discovery.TokenEndpoint, discovery.JWKSURI} {
+		if err := validateAuthURL(endpoint, o.allowInsecureHTTP); err != nil {
+			return nil, fmt.Errorf("invalid oidc endpoint %q: %w", endpoint, err)
+		}
+	}
+	o.discovery = &discovery
+	return o.discovery, nil
+}
+
+// signingKeys 返回匹配的签名公钥，找不到时重新拉取 JWKS 以支持密钥轮换
+func (o *ProviderOIDC) signingKeys(ctx context.Context, kid, alg string) ([]crypto.PublicKey, error) {
+	discovery, err := o.loadDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		if attempt > 0 || o.keys == nil {
+			if o.keys, err = o.fetchKeys(ctx, discovery.JWKSURI); err != nil {
+				return nil, err
+			}
+		}
+		result := make([]crypto.PublicKey, 0, 1)
+		for _, key := range o.keys {
+			if (kid == "" || key.id == kid) && (key.alg == "" || key.alg == alg) {
+				result = append(result, key.key)
+			}
+		}
+		if len(result) > 0 {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("oidc signing key %q not found", kid)
+}
+
+func (o *ProviderOIDC) fetchKeys(ctx context.Context, jwksURI string) ([]oidcKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(ctx, jwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("oidc jwks fetch failed: %w", err)
+	}
+	keys := make([]oidcKey, 0, len(jwks.Keys))
+	for _, item := range jwks.Keys {
+		if item.Use != "" && item.Use != "sig" {
+			continue
+		}
+		switch {
+		case item.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(item.N)
+			e, errE := base64.RawURLEncoding.DecodeString(item.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				continue
+			}
+			keys = append(keys, oidcKey{id: item.Kid, alg: item.Alg, key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}})
+		case item.Kty == "EC" && item.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(item.X)
+			y, errY := base64.RawURLEncoding.DecodeString(item.Y)
+			if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+				continue
+			}
+			key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+			if err != nil {
+				continue
+			}
+			keys = append(keys, oidcKey{id: item.Kid, alg: item.Alg, key: key})
+		}
+	}
+	return keys, nil
+}
+
+// verifyIDToken 校验 ID Token 的签名 (RS256 / ES256)、签发方、受众、有效期与 nonce，返回全部声明
+func (o *ProviderOIDC) verifyIDToken(ctx context.Context, raw, nonce string) (map[string]any, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed id token header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err = json.Unmarshal(headerData, &header); err != nil {
+		return nil, errors.New("malformed id token header")
+	}
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return nil, fmt.Errorf("unsupported id token algorithm %q", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed id token signature")
+	}
+	keys, err := o.signingKeys(ctx, header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	verified := false
+	for _, key := range keys {
+		switch pub := key.(type) {
+		case *rsa.PublicKey:
+			verified = header.Alg == "RS256" && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+		case *ecdsa.PublicKey:
+			verified = header.Alg == "ES256" && len(signature) == 64 &&
+				ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:]))
+		}
+		if verified {
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid id token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed id token payload")
+	}
+	var claims oidcClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("malformed id token payload")
+	}
+	now := time.Now()
+	switch {
+	case strings.TrimRight(claims.Issuer, "/") != o.issuer:
+		return nil, errors.New("id token issuer mismatch")
+	case !slices.Contains(claims.Audience, o.clientID):
+		return nil, errors.New("id token audience mismatch")
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != o.clientID:
+		return nil, errors.New("id token authorized party mismatch")
+	case claims.Subject == "":
+		return nil, errors.New("id token missing subject")
+	case claims.Expiry == 0 || now.Add(-oidcClockSkew).After(time.Unix(int64(claims.Expiry), 0)):
+		return nil, errors.New("id token expired")
+	case claims.IssuedAt != 0 && now.Add(oidcClockSkew).Before(time.Unix(int64(claims.IssuedAt), 0)):
+		return nil, errors.New("id token issued in the future")
+	case !hmac.Equal([]byte(claims.Nonce), []byte(nonce)):
+		return nil, errors.New("id token nonce mismatch")
+	}
+	var all map[string]any
+	if err = json.Unmarshal(payload, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (o *ProviderOIDC) getJSON(ctx context.Context, target string, value any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+func oidcSessionGroups(sess *core.AuthSession) ([]string, error) {
+	var private oidcAuthSession
+	if len(sess.Private) > 0 {
+		if err := json.Unmarshal(sess.Private, &private); err != nil {
+			return nil, err
+		}
+	}
+	return private.Groups, nil
+}
+
+// oidcClaimStrings 读取字符串或字符串列表声明，并按分组规则规范化
+func oidcClaimStrings(value any) []string {
+	switch item := value.(type) {
+	case string:
+		return normalizeOIDCGroups([]string{item})
+	case []any:
+		result := make([]string, 0, len(item))
+		for _, v := range item {
+			if s, ok := v.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return normalizeOIDCGroups(result)
+	}
+	return nil
+}
+
+// normalizeOIDCGroups 去除 Keycloak 风格分组路径的前导 /，并统一为小写
+func normalizeOIDCGroups(groups []string) []string {
+	result := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if group = strings.ToLower(strings.Trim(strings.TrimSpace(group), "/")); group != "" {
+			result = append(result, group)
+		}
+	}
+	return result
+}
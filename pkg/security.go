@@ -63,6 +63,7 @@ func isWebSocketUpgrade(req *http.Request) bool {
 type securityResponseWriter struct {
 	http.ResponseWriter
 	security core.SecurityResult
+	headers  http.Header // headers 过滤器声明的响应头，写入非错误响应时覆盖已有值
 	wrote    bool
 }
 
@@ -78,6 +79,12 @@ func (w *securityResponseWriter) WriteHeader(statusCode int) {
 	if !w.wrote {
 		w.wrote = true
 		headers := w.ResponseWriter.Header()
+		if statusCode < http.StatusBadRequest {
+			// 错误响应不沿用路径的缓存等策略
+			for name, values := range w.headers {
+				headers[name] = values
+			}
+		}
 		writeSecurityHeaders(headers, w.security)
 		if !w.security.AllowResponseCookies {
 			headers.Del("Set-Cookie")
@@ -86,6 +93,14 @@ func (w *securityResponseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// ResponseHeaders 返回写入时覆盖的自定义响应头
+func (w *securityResponseWriter) ResponseHeaders() http.Header {
+	if w.headers == nil {
+		w.headers = make(http.Header)
+	}
+	return w.headers
+}
+
 func (w *securityResponseWriter) Write(data []byte) (int, error) {
 	if !w.wrote {
 		w.WriteHeader(http.StatusOK)
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"path"
 	"regexp"
 	"slices"
 	"strings"
@@ -26,12 +27,14 @@ import (
 
 var portExp = regexp.MustCompile(`:\d+$`)
 
+// maxRewrites 单个请求内部改写的次数上限，避免规则循环
+const maxRewrites = 8
+
 type Server struct {
 	backend      core.Backend
 	meta         *core.PageDomain
 	db           kv.KV
 	userDB       kv.KV
-	filterMgr    map[string]core.FilterInstance
 	trustedProxy *core.TrustedProxyPolicy
 
 	globCache *lru.Cache[string, glob.Glob]
@@ -43,6 +46,7 @@ type Server struct {
 	event        subscribe.Subscriber
 	updateHub    *core.RepoUpdateHub
 	auth         *core.AuthService
+	passwords    *core.PasswordService
 	errorHandler func(w http.ResponseWriter, r *http.Request, err error)
 }
 
@@ -61,6 +65,10 @@ type serverConfig struct {
 	filterServerConfig         core.FilterServerConfig
 	trustedProxies             []string
 	authService                *core.AuthService
+	secret                     []byte
+	repoSubdomain              bool
+	orgConfigRepo              string
+	lookupCNAME                func(ctx context.Context, host string) (string, error)
 }
 
 type ServerOption func(*serverConfig)
@@ -129,6 +137,34 @@ func WithAuth(authService *core.AuthService) ServerOption {
 	}
 }
 
+// WithSecret 设置服务端签名密钥，用于访问口令 Cookie 等；为空时使用随机密钥
+func WithSecret(secret []byte) ServerOption {
+	return func(c *serverConfig) {
+		c.secret = secret
+	}
+}
+
+// WithRepoSubdomain 启用 <repo>.<owner>.<domain> 子域名托管模式
+func WithRepoSubdomain(enabled bool) ServerOption {
+	return func(c *serverConfig) {
+		c.repoSubdomain = enabled
+	}
+}
+
+// WithCNAMEResolver 设置查询未绑定域名 CNAME 记录的函数，默认使用系统解析器
+func WithCNAMEResolver(lookup func(ctx context.Context, host string) (string, error)) ServerOption {
+	return func(c *serverConfig) {
+		c.lookupCNAME = lookup
+	}
+}
+
+// WithOrgConfigRepo 设置组织级配置仓库名称，默认为 .pages
+func WithOrgConfigRepo(name string) ServerOption {
+	return func(c *serverConfig) {
+		c.orgConfigRepo = name
+	}
+}
+
 func NewPageServer(
 	backend core.Backend,
 	domain string,
@@ -137,8 +173,9 @@ func NewPageServer(
 	opts ...ServerOption,
 ) (*Server, error) {
 	cfg := &serverConfig{
-		client:       http.DefaultClient,
-		filterConfig: make(map[string]map[string]any),
+		client:        http.DefaultClient,
+		filterConfig:  make(map[string]map[string]any),
+		orgConfigRepo: core.DefaultOrgConfigRepo,
 		filterServerConfig: core.FilterServerConfig{
 			StaticCacheControl:  "public, max-age=60",
 			MaxRequestBodyBytes: 4 << 20,
@@ -201,11 +238,7 @@ func NewPageServer(
 	if err != nil {
 		return nil, err
 	}
-	enabledFilters := make([]string, 0, len(defaultFilters))
-	for name := range defaultFilters {
-		enabledFilters = append(enabledFilters, name)
-	}
-	svcMeta := core.NewServerMeta(
+	svcMeta, err := core.NewServerMeta(
 		cfg.client,
 		backend,
 		domain,
@@ -214,10 +247,18 @@ func NewPageServer(
 		cfg.cacheMetaTTL,
 		cfg.cacheMetaRefresh,
 		cfg.cacheMetaRefreshConcurrent,
-		enabledFilters,
+		defaultFilters,
 		updateHub,
 	)
+	if err != nil {
+		return nil, err
+	}
+	svcMeta.OrgRepo = cfg.orgConfigRepo
 	pageMeta := core.NewPageDomain(svcMeta, domain)
+	pageMeta.RepoSubdomain = cfg.repoSubdomain
+	if cfg.lookupCNAME != nil {
+		pageMeta.LookupCNAME = cfg.lookupCNAME
+	}
 	var trustedProxy *core.TrustedProxyPolicy
 	if len(cfg.trustedProxies) > 0 {
 		trustedProxy, err = core.NewTrustedProxyPolicy(cfg.trustedProxies)
@@ -225,13 +266,19 @@ func NewPageServer(
 			return nil, err
 		}
 	}
+	if cfg.authService != nil {
+		// 页面域名及已绑定别名下需要登录的页面可接收跨域登录票据
+		cfg.authService.SetHostPolicy(func(ctx context.Context, host, path string) bool {
+			page, err := pageMeta.ParseDomainMeta(ctx, host, path)
+			return err == nil && page.RequiresLogin()
+		})
+	}
 	return &Server{
 		backend:      backend,
 		meta:         pageMeta,
 		db:           db,
 		userDB:       userDB,
 		globCache:    globCache,
-		filterMgr:    defaultFilters,
 		trustedProxy: trustedProxy,
 		errorHandler: cfg.errorHandler,
 		cacheBlob:    cfg.cacheBlob,
@@ -240,6 +287,7 @@ func NewPageServer(
 		event:        cfg.event,
 		updateHub:    updateHub,
 		auth:         cfg.authService,
+		passwords:    core.NewPasswordService(cfg.secret, 0, db.Child("password-failures")),
 	}, nil
 }
 
@@ -250,10 +298,21 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, request *http.Request) {
 	request = request.WithContext(core.ContextWithRequestInfo(request.Context(), requestInfo))
 	var meta *core.PageContent
 	var err error
-	if !core.IsReservedPath(request.URL.Path) {
-		domain := portExp.ReplaceAllString(strings.ToLower(request.Host), "")
+	domain := portExp.ReplaceAllString(strings.ToLower(request.Host), "")
+	switch {
+	case (request.URL.Path == core.AuthPathMe || request.URL.Path == core.AuthPathShare) && s.auth != nil:
+		// 登录状态与分享接口按 path 参数指定的页面解析，使页面的跨域配置生效
+		meta, _ = s.meta.ParseDomainMeta(request.Context(), domain, reservedPagePath(request, "path"))
+	case request.URL.Path == core.PasswordPath:
+		meta, _ = s.meta.ParseDomainMeta(request.Context(), domain, reservedPagePath(request, "return_to"))
+	case !core.IsReservedPath(request.URL.Path):
 		meta, err = s.meta.ParseDomainMeta(request.Context(), domain, request.URL.Path)
 		if err != nil {
+			var cfgErr *core.PageConfigError
+			if errors.As(err, &cfgErr) {
+				w.Header().Set("Cache-Control", "no-store")
+				err = s.configError(request, cfgErr)
+			}
 			s.handleRequestError(w, request, sessionID, err)
 			return
 		}
@@ -297,15 +356,15 @@ func (s *Server) handleRequestError(writer http.ResponseWriter, request *http.Re
 
 func (s *Server) servePage(writer http.ResponseWriter, request *http.Request, meta *core.PageContent) error {
 	if core.IsReservedPath(request.URL.Path) {
-		if s.auth == nil {
-			http.NotFound(writer, request)
-			return nil
-		}
-		return s.auth.Handle(writer, request)
+		return s.serveReserved(writer, request, meta)
+	}
+	if target, ok := s.meta.SubdomainRedirect(meta, request.URL, core.RequestInfoFromRequest(request).Scheme); ok {
+		http.Redirect(writer, request, target, http.StatusMovedPermanently)
+		return nil
 	}
 	var err error
 	if s.auth != nil {
-		if err = s.auth.AttachAuth(request); err != nil {
+		if err = s.auth.AttachAuth(writer, request); err != nil {
 			return err
 		}
 	}
@@ -318,6 +377,10 @@ func (s *Server) servePage(writer http.ResponseWriter, request *http.Request, me
 			return authErr
 		}
 	}
+	vfs := core.NewPageVFS(s.backend, meta.Owner, meta.Repo, meta.CommitID)
+	if meta.Password != nil && !s.passwords.Require(writer, request, meta, meta.Password, vfs) {
+		return nil
+	}
 	writer.Header().Set("X-Page-ID", meta.CommitID)
 	cancelCtx, cancelFunc := context.WithCancel(request.Context())
 	releaseUpdate, err := s.updateHub.Attach(meta.Owner, meta.Repo, meta.CommitID, request.Header.Get("Session-ID"), cancelFunc)
@@ -332,7 +395,7 @@ func (s *Server) servePage(writer http.ResponseWriter, request *http.Request, me
 	filterCtx := core.FilterContext{
 		PageContent:  meta,
 		Context:      cancelCtx,
-		PageVFS:      core.NewPageVFS(s.backend, meta.Owner, meta.Repo, meta.CommitID),
+		PageVFS:      vfs,
 		Cache:        tools.NewTTLCache(s.cacheBlob.Child("filter", meta.Owner, meta.Repo, meta.CommitID), s.cacheBlobTTL),
 		OrgDB:        s.userDB.Child("org", meta.Owner),
 		RepoDB:       s.userDB.Child("repo", meta.Owner, meta.Repo),
@@ -340,45 +403,58 @@ func (s *Server) servePage(writer http.ResponseWriter, request *http.Request, me
 		VersionEvent: s.event.Child("version", meta.Owner, meta.Repo, meta.CommitID),
 		SharedEvent:  s.event.Child("shared", meta.Owner, meta.Repo),
 		Auth:         core.AuthInfoFromContext(request.Context()),
+		Protected:    meta.Password != nil,
 
 		Kill: cancelFunc,
 	}
+	if w, ok := writer.(*securityResponseWriter); ok {
+		filterCtx.ResponseHeaders = w.ResponseHeaders()
+	}
+	filterCtx.Authorize = func(writer http.ResponseWriter, request *http.Request, access *core.PageAccess) (bool, error) {
+		if s.auth == nil {
+			return false, errors.New("auth route requires auth provider")
+		}
+		return s.auth.RequireAccess(writer, request, meta, access)
+	}
+
+	filterCtx.Share = func(target string, ttl time.Duration, ip string) (string, time.Time, error) {
+		sess, ok := core.AuthSessionFromContext(request.Context())
+		if s.auth == nil || !ok {
+			return "", time.Time{}, errors.New("share requires login")
+		}
+		token, expireAt, err := s.auth.CreateShare(request.Context(), sess, meta, target, ttl, ip)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return core.ShareURL(request, sharePagePath(meta, target), token), expireAt, nil
+	}
+	filterCtx.RequirePassword = func(writer http.ResponseWriter, request *http.Request, password *core.PagePassword) bool {
+		return s.passwords.Require(writer, request, meta, password, vfs)
+	}
 
 	slog.Debug("new request", "request path", meta.Path)
 
-	if strings.HasSuffix(meta.Path, "/") || meta.Path == "" {
-		meta.Path += "index.html"
+	meta.Path = pageFilePath(meta.Path)
+	table, err := s.meta.RouteTable(meta.Owner, meta.Repo, meta.PageMetaContent)
+	if err != nil {
+		return err
 	}
-	activeFiltersCall := make([]core.FilterCall, 0)
-	activeFilters := make([]core.Filter, 0)
-	filtersRoute := make([]string, 0)
+	return s.serveFilters(filterCtx, writer, request, table, 0)
+}
 
-	for _, filter := range meta.Filters {
-		value, ok := s.globCache.Get(filter.Path)
-		if !ok {
-			value, err = glob.Compile(filter.Path)
-			if err != nil {
-				slog.Warn("invalid glob pattern", "pattern", filter.Path, "error", err)
-				continue
-			}
-			s.globCache.Add(filter.Path, value)
-		}
-		if value.Match(meta.Path) {
-			instance := s.filterMgr[filter.Type]
-			if instance == nil {
-				return fmt.Errorf("filter %q became unavailable after metadata validation", filter.Type)
-			}
-			activeFilters = append(activeFilters, filter)
-			filtersRoute = append(filtersRoute, fmt.Sprintf("%s[%s]%s", filter.Type, filter.Path, filter.Params))
-			call, err := instance(filter.Params)
-			if err != nil {
-				return err
-			}
-			activeFiltersCall = append(activeFiltersCall, call)
+// serveFilters 按页面路径匹配路由并执行过滤器链，rewrite 以新路径重新进入
+func (s *Server) serveFilters(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, table *core.RouteTable, rewrites int) error {
+	host := portExp.ReplaceAllString(strings.ToLower(request.Host), "")
+	hits := table.MatchPage(ctx.Path, func(route *core.Route) bool {
+		return route.Match.Matches(request, host, s.compileGlob)
+	})
+	filtersRoute := make([]string, 0, len(hits)*2)
+	for _, hit := range hits {
+		if hit.Err != nil {
+			return hit.Err
 		}
+		filtersRoute = append(filtersRoute, fmt.Sprintf("%s[%s]%s", hit.Type, hit.Path, hit.Params))
 	}
-	slices.Reverse(activeFiltersCall)
-	slices.Reverse(activeFilters)
 
 	// Build the visual call stack for logging (e.g., A -> B -> C -> B -> A)
 	l := len(filtersRoute)
@@ -389,10 +465,56 @@ func (s *Server) servePage(writer http.ResponseWriter, request *http.Request, me
 	}
 	slog.Debug("active filters", "filters", strings.Join(filtersRoute, " -> "))
 
+	ctx.Rewrite = func(ctx core.FilterContext, writer http.ResponseWriter, request *http.Request, target string) error {
+		if rewrites >= maxRewrites {
+			return fmt.Errorf("too many rewrites for %s", request.URL.Path)
+		}
+		dst := strings.TrimPrefix(path.Clean("/"+target), "/")
+		if dst != "" && strings.HasSuffix(target, "/") {
+			dst += "/"
+		}
+		page := *ctx.PageContent
+		page.Path = pageFilePath(dst)
+		slog.Debug("rewrite", "src", ctx.Path, "dst", page.Path)
+		ctx.PageContent = &page
+		return s.serveFilters(ctx, writer, request, table, rewrites+1)
+	}
 	var stack core.NextCall = core.NotFountNextCall
-	for i, filter := range activeFiltersCall {
-		stack = core.NextCallWrapper(filter, stack, activeFilters[i])
+	for _, hit := range slices.Backward(hits) {
+		stack = core.NextCallWrapper(hit.Call, stack, hit.Filter, hit.RouteParams)
+	}
+	return stack(ctx, writer, request)
+}
+
+// sharePagePath 仓库内路径在当前域名下的地址，保留目录结尾的 /
+func sharePagePath(meta *core.PageContent, target string) string {
+	result := strings.TrimSuffix(meta.BasePath, "/") + path.Clean("/"+target)
+	if strings.HasSuffix(target, "/") && !strings.HasSuffix(result, "/") {
+		result += "/"
+	}
+	return result
+}
+
+// pageFilePath 目录路径指向其中的 index.html
+func pageFilePath(path string) string {
+	if strings.HasSuffix(path, "/") || path == "" {
+		path += "index.html"
+	}
+	return path
+}
+
+func (s *Server) compileGlob(pattern string, separators ...rune) (glob.Glob, error) {
+	key := pattern
+	if len(separators) > 0 {
+		key = string(separators) + "\x00" + pattern
+	}
+	if value, ok := s.globCache.Get(key); ok {
+		return value, nil
+	}
+	value, err := glob.Compile(pattern, separators...)
+	if err != nil {
+		return nil, err
 	}
-	err = stack(filterCtx, writer, request)
-	return err
+	s.globCache.Add(key, value)
+	return value, nil
 }
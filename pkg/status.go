@@ -0,0 +1,210 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+// serveReserved 分发 /.pages/ 下的内置接口，meta 仅在登录状态、分享接口与口令表单解析到页面时非空
+func (s *Server) serveReserved(writer http.ResponseWriter, request *http.Request, meta *core.PageContent) error {
+	if strings.HasPrefix(request.URL.Path, core.StatusPathPrefix) {
+		return s.serveStatus(writer, request)
+	}
+	if request.URL.Path == core.PasswordPath {
+		return s.servePassword(writer, request, meta)
+	}
+	if s.auth == nil {
+		http.NotFound(writer, request)
+		return nil
+	}
+	switch request.URL.Path {
+	case core.AuthPathMe:
+		return s.serveAuthMe(writer, request, meta)
+	case core.AuthPathShare:
+		return s.auth.HandleShare(writer, request, meta, reservedPagePath(request, "path"))
+	}
+	return s.auth.Handle(writer, request)
+}
+
+// serveAuthMe 返回当前登录用户以及其对 path 参数所指页面仓库的权限
+func (s *Server) serveAuthMe(writer http.ResponseWriter, request *http.Request, meta *core.PageContent) error {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return nil
+	}
+	if err := s.auth.AttachAuth(writer, request); err != nil {
+		return err
+	}
+	me := core.AuthMe{
+		AuthInfo:  core.AuthInfoFromContext(request.Context()),
+		LoginURL:  core.AuthPathLogin + "?return_to=" + url.QueryEscape(reservedPagePath(request, "path")),
+		LogoutURL: core.AuthPathLogout,
+	}
+	if meta != nil {
+		var err error
+		me.Access = !meta.Private
+		if !me.Access {
+			if me.Access, err = s.canAccessPage(request, meta.Owner, meta.Repo, meta.PageMetaContent); err != nil {
+				return err
+			}
+		}
+		if me.Access {
+			me.Owner, me.Repo = meta.Owner, meta.Repo
+			if sess, ok := core.AuthSessionFromContext(request.Context()); ok {
+				if me.Permission, err = s.auth.RepoPermission(request.Context(), sess, meta.Owner, meta.Repo); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer.Header().Set("Cache-Control", "no-store")
+	return json.NewEncoder(writer).Encode(me)
+}
+
+// servePassword 口令表单，按 return_to 与 scope 参数查找页面及其口令配置
+func (s *Server) servePassword(writer http.ResponseWriter, request *http.Request, meta *core.PageContent) error {
+	var password *core.PagePassword
+	if meta != nil {
+		password = meta.PasswordConfig(meta.Owner, meta.Repo, request.URL.Query().Get("scope"))
+	}
+	if password == nil {
+		http.NotFound(writer, request)
+		return nil
+	}
+	vfs := core.NewPageVFS(s.backend, meta.Owner, meta.Repo, meta.CommitID)
+	return s.passwords.Handle(writer, request, meta, password, vfs)
+}
+
+// reservedPagePath 内置接口通过参数指定的页面路径，不含查询参数，默认为站点根路径
+func reservedPagePath(request *http.Request, key string) string {
+	value := request.URL.Query().Get(key)
+	if !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") || strings.HasPrefix(value, "/\\") {
+		return "/"
+	}
+	if index := strings.IndexAny(value, "?#"); index >= 0 {
+		value = value[:index]
+	}
+	return value
+}
+
+// serveStatus 返回仓库页面状态，配置错误详情仅对有写权限的用户可见
+func (s *Server) serveStatus(writer http.ResponseWriter, request *http.Request) error {
+	owner, repo, ok := core.ParseStatusPath(request.URL.Path)
+	if !ok {
+		http.NotFound(writer, request)
+		return nil
+	}
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return nil
+	}
+	status := core.PageStatus{Owner: owner, Repo: repo, Status: core.PageStatusOK}
+	code := http.StatusOK
+	var meta *core.PageMetaContent
+	var err error
+	if repo == s.meta.OrgRepo {
+		err = os.ErrNotExist
+	} else {
+		meta, err = s.meta.GetMeta(request.Context(), owner, repo)
+	}
+	var cfgErr *core.PageConfigError
+	switch {
+	case err == nil:
+		visible := !meta.Private
+		if !visible {
+			if visible, err = s.canAccessPage(request, owner, repo, meta); err != nil {
+				return err
+			}
+		}
+		if visible {
+			status.CommitID = meta.CommitID
+		} else {
+			status.Status = core.PageStatusNotFound
+			code = http.StatusNotFound
+		}
+	case errors.As(err, &cfgErr):
+		writable, err := s.canWriteRepo(request, owner, repo)
+		if err != nil {
+			return err
+		}
+		// 配置无法解析时不确定仓库是否私有，启用认证时仅对可访问仓库的用户展示错误状态
+		visible := writable || s.auth == nil
+		if !visible {
+			if visible, err = s.canAccessPage(request, owner, repo, nil); err != nil {
+				return err
+			}
+		}
+		switch {
+		case writable:
+			status.Status = core.PageStatusError
+			status.CommitID = cfgErr.CommitID
+			status.Error = cfgErr.Message
+		case visible:
+			status.Status = core.PageStatusError
+		default:
+			status.Status = core.PageStatusNotFound
+			code = http.StatusNotFound
+		}
+	case errors.Is(err, os.ErrNotExist):
+		status.Status = core.PageStatusNotFound
+		code = http.StatusNotFound
+	default:
+		return err
+	}
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(code)
+	return json.NewEncoder(writer).Encode(status)
+}
+
+// configError 拥有写权限的用户看到完整的配置错误，其余用户只看到通用错误
+func (s *Server) configError(request *http.Request, cfgErr *core.PageConfigError) error {
+	writable, err := s.canWriteRepo(request, cfgErr.Owner, cfgErr.Repo)
+	if err != nil {
+		slog.Warn("check repo write permission failed", "owner", cfgErr.Owner, "repo", cfgErr.Repo, "error", err)
+		return cfgErr
+	}
+	if writable {
+		return errors.New(cfgErr.Detail())
+	}
+	return cfgErr
+}
+
+func (s *Server) canAccessPage(request *http.Request, owner, repo string, meta *core.PageMetaContent) (bool, error) {
+	sess, err := s.requestSession(request)
+	if err != nil || sess == nil {
+		return false, err
+	}
+	return s.auth.CanAccessPage(request.Context(), sess, owner, repo, meta)
+}
+
+func (s *Server) canWriteRepo(request *http.Request, owner, repo string) (bool, error) {
+	sess, err := s.requestSession(request)
+	if err != nil || sess == nil {
+		return false, err
+	}
+	return s.auth.CanWriteRepo(request.Context(), sess, owner, repo)
+}
+
+// requestSession 读取请求携带的登录会话，未启用认证或未登录时返回 nil
+func (s *Server) requestSession(request *http.Request) (*core.AuthSession, error) {
+	if s.auth == nil {
+		return nil, nil
+	}
+	if sess, ok := core.AuthSessionFromContext(request.Context()); ok {
+		return sess, nil
+	}
+	if err := s.auth.AttachAuth(nil, request); err != nil {
+		return nil, err
+	}
+	sess, _ := core.AuthSessionFromContext(request.Context())
+	return sess, nil
+}
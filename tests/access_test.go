@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+type teamAuthProvider struct {
+	fakeAuthProvider
+	teams map[string]bool
+	calls int
+}
+
+func (p *teamAuthProvider) RepoPermission(context.Context, *core.AuthSession, string, string) (string, error) {
+	return core.PermissionRead, nil
+}
+
+func (p *teamAuthProvider) IsOrgMember(context.Context, *core.AuthSession, string) (bool, error) {
+	return false, nil
+}
+
+func (p *teamAuthProvider) IsTeamMember(_ context.Context, _ *core.AuthSession, org, team string) (bool, error) {
+	p.calls++
+	return p.teams[org+"/"+team], nil
+}
+
+func Test_AccessRulesRestrictPublicRepo(t *testing.T) {
+	provider := &teamAuthProvider{
+		fakeAuthProvider: fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true},
+		teams:            map[string]bool{"org1/handbook": true},
+	}
+	server := newAuthTestServer(t, provider)
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "handbook")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+access:
+  teams: [org1/handbook]
+`)
+
+	// 声明访问规则后无需 private: true 即要求登录
+	_, resp, err := server.OpenRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	loginThroughAuth(t, server, "/repo1/")
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, "handbook", string(data))
+
+	// 判断结果进入授权缓存
+	_, _, err = server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.calls)
+
+	// 仓库可读但不在团队中时拒绝访问
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+access:
+  teams: [org1/other]
+`)
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func Test_AccessRulesInvalid(t *testing.T) {
+	server := newAuthTestServer(t, &fakeAuthProvider{})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "handbook")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+access:
+  permission: owner
+`)
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
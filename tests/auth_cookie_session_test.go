@@ -0,0 +1,217 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+// countingKV 统计会话记录的读取次数
+type countingKV struct {
+	kv.KV
+	prefix string
+	reads  *int
+}
+
+func (c countingKV) Child(paths ...string) kv.KV {
+	return countingKV{KV: c.KV.Child(paths...), prefix: c.prefix + strings.Join(paths, "/"), reads: c.reads}
+}
+
+func (c countingKV) Get(ctx context.Context, key string) (string, error) {
+	if c.prefix == "session" {
+		*c.reads++
+	}
+	return c.KV.Get(ctx, key)
+}
+
+func newCookieSessionServer(t *testing.T, store kv.KV, keys ...string) (*testcore.TestServer, *core.AuthService) {
+	t.Helper()
+	sessionKeys := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		sessionKeys = append(sessionKeys, []byte(key))
+	}
+	auth := core.NewAuthService(&sessionAuthProvider{}, store, core.AuthServiceConfig{
+		CookieName:   "test_session",
+		SessionStore: core.SessionStoreCookie,
+		SessionKeys:  sessionKeys,
+	})
+	server := testcore.NewTestServerOptions("example.com", pkg.WithAuth(auth))
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "private: true\n")
+	return server, auth
+}
+
+// cookieLogin 完成登录并返回下发的会话 Cookie
+func cookieLogin(t *testing.T, server *testcore.TestServer) *http.Cookie {
+	t.Helper()
+	_, resp, err := server.OpenFile("https://org1.example.com/.pages/auth/login?return_to=" + url.QueryEscape("/repo1/"))
+	require.NoError(t, err)
+	state := strings.TrimPrefix(resp.Header.Get("Location"), "/provider-login?state=")
+	_, resp, err = server.OpenFile("https://org1.example.com/.pages/auth/callback?code=ok&state=" + state)
+	require.NoError(t, err)
+	require.Len(t, resp.Cookies(), 1)
+	return resp.Cookies()[0]
+}
+
+// openWithCookie 以另一位访客的身份携带指定 Cookie 访问页面
+func openWithCookie(t *testing.T, server *testcore.TestServer, cookie *http.Cookie) *http.Response {
+	t.Helper()
+	server.ClearCookies()
+	req := httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	_, resp, err := server.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func Test_CookieSessionSkipsKV(t *testing.T) {
+	memory, err := kv.NewMemory("")
+	require.NoError(t, err)
+	reads := 0
+	server, _ := newCookieSessionServer(t, countingKV{KV: memory, reads: &reads}, "key-1")
+	defer server.Close()
+
+	cookie := cookieLogin(t, server)
+	assert.True(t, strings.HasPrefix(cookie.Value, "v1."))
+	assert.NotContains(t, cookie.Value, "dragon")
+	for range 3 {
+		data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+		require.NoError(t, err)
+		assert.Equal(t, "private", string(data))
+	}
+	assert.Zero(t, reads)
+
+	// 篡改的 Cookie 视为未登录
+	tampered := *cookie
+	tampered.Value = cookie.Value[:len(cookie.Value)-4] + "AAAA"
+	assert.Equal(t, http.StatusFound, openWithCookie(t, server, &tampered).StatusCode)
+}
+
+func Test_CookieSessionRevoke(t *testing.T) {
+	store, err := kv.NewMemory("")
+	require.NoError(t, err)
+	server, auth := newCookieSessionServer(t, store, "key-1")
+	defer server.Close()
+
+	cookie := cookieLogin(t, server)
+	sessions := listSessions(t, server)
+	require.Len(t, sessions, 1)
+	assert.True(t, sessions[0].Current)
+
+	_, resp, err := server.OpenRequest(http.MethodDelete, "https://org1.example.com/.pages/auth/sessions?id="+sessions[0].ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	// 撤销后重放原 Cookie 同样失效
+	assert.Equal(t, http.StatusFound, openWithCookie(t, server, cookie).StatusCode)
+
+	// 其他实例共享撤销列表
+	cookie = cookieLogin(t, server)
+	other, _ := newCookieSessionServer(t, store, "key-1")
+	defer other.Close()
+	assert.Equal(t, http.StatusOK, openWithCookie(t, other, cookie).StatusCode)
+	require.NoError(t, auth.RevokeSubject(context.Background(), "u1"))
+	assert.Equal(t, http.StatusFound, openWithCookie(t, server, cookie).StatusCode)
+	fresh, _ := newCookieSessionServer(t, store, "key-1")
+	defer fresh.Close()
+	assert.Equal(t, http.StatusFound, openWithCookie(t, fresh, cookie).StatusCode)
+}
+
+func Test_CookieSessionKeyRotation(t *testing.T) {
+	store, err := kv.NewMemory("")
+	require.NoError(t, err)
+	server, _ := newCookieSessionServer(t, store, "key-1")
+	defer server.Close()
+	cookie := cookieLogin(t, server)
+
+	// 新密钥在前，旧密钥仍可解密已签发的会话
+	rotated, _ := newCookieSessionServer(t, store, "key-2", "key-1")
+	defer rotated.Close()
+	assert.Equal(t, http.StatusOK, openWithCookie(t, rotated, cookie).StatusCode)
+	rotatedCookie := cookieLogin(t, rotated)
+	assert.Equal(t, http.StatusFound, openWithCookie(t, server, rotatedCookie).StatusCode)
+
+	// 移除旧密钥后旧会话失效
+	removed, _ := newCookieSessionServer(t, store, "key-2")
+	defer removed.Close()
+	assert.Equal(t, http.StatusFound, openWithCookie(t, removed, cookie).StatusCode)
+	assert.Equal(t, http.StatusOK, openWithCookie(t, removed, rotatedCookie).StatusCode)
+}
+
+// recordingKV 记录写入 KV 的全部内容
+type recordingKV struct {
+	kv.KV
+	values *[]string
+}
+
+func (r recordingKV) Child(paths ...string) kv.KV {
+	return recordingKV{KV: r.KV.Child(paths...), values: r.values}
+}
+
+func (r recordingKV) Put(ctx context.Context, key, value string, ttl time.Duration) error {
+	*r.values = append(*r.values, value)
+	return r.KV.Put(ctx, key, value, ttl)
+}
+
+func (r recordingKV) PutIfNotExists(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	*r.values = append(*r.values, value)
+	return r.KV.PutIfNotExists(ctx, key, value, ttl)
+}
+
+func (r recordingKV) CompareAndSwap(ctx context.Context, key, oldValue, newValue string) (bool, error) {
+	*r.values = append(*r.values, newValue)
+	return r.KV.CompareAndSwap(ctx, key, oldValue, newValue)
+}
+
+func Test_CookieSessionKVRecordOmitsProviderData(t *testing.T) {
+	memory, err := kv.NewMemory("")
+	require.NoError(t, err)
+	var values []string
+	session := authSession("u1", "dragon")
+	session.Private = []byte(`"provider-token"`)
+	provider := &privateAuthProvider{fakeAuthProvider: fakeAuthProvider{session: session}}
+	server, auth := newSSOTestServerConfig(t, provider, recordingKV{KV: memory, values: &values}, core.AuthServiceConfig{
+		SessionStore: core.SessionStoreCookie,
+		SessionKeys:  [][]byte{[]byte("key-1")},
+	})
+	defer server.Close()
+
+	_, resp, err := server.OpenFile(ssoLogin(t, server, "docs.corp.com"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	data, _, err := server.OpenFile("https://docs.corp.com/")
+	require.NoError(t, err)
+	assert.Equal(t, "private", string(data))
+	// 派生会话自身的 Cookie 携带提供方数据
+	assert.Equal(t, []string{`"provider-token"`}, provider.seen)
+
+	// KV 仅保存会话列表与撤销使用的记录
+	require.NotEmpty(t, values)
+	for _, value := range values {
+		assert.NotContains(t, value, "provider-token")
+		assert.NotContains(t, value, "dragon")
+	}
+	sessions, err := auth.Sessions(context.Background(), "u1")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	for _, sess := range sessions {
+		assert.NotEmpty(t, sess.Host)
+		assert.False(t, sess.CreatedAt.IsZero())
+	}
+
+	// 撤销统一登录会话后派生会话同样失效
+	require.NoError(t, auth.RevokeSubject(context.Background(), "u1"))
+	_, resp, err = server.OpenFile("https://docs.corp.com/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
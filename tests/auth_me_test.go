@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func authMe(t *testing.T, server *testcore.TestServer, target string) core.AuthMe {
+	t.Helper()
+	data, resp, err := server.OpenFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	var me core.AuthMe
+	require.NoError(t, json.Unmarshal(data, &me))
+	return me
+}
+
+func Test_AuthMePrivateRepo(t *testing.T) {
+	server := newAuthTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "private: true\n")
+
+	me := authMe(t, server, "https://org1.example.com/.pages/auth/me?path=/repo1/docs/")
+	assert.False(t, me.Authenticated)
+	assert.False(t, me.Access)
+	assert.Empty(t, me.Repo)
+	assert.Equal(t, "/.pages/auth/login?return_to=%2Frepo1%2Fdocs%2F", me.LoginURL)
+	assert.Equal(t, core.AuthPathLogout, me.LogoutURL)
+
+	loginThroughAuth(t, server, "/repo1/")
+	me = authMe(t, server, "https://org1.example.com/.pages/auth/me?path=/repo1/")
+	assert.True(t, me.Authenticated)
+	require.NotNil(t, me.Identity)
+	assert.Equal(t, "dragon", me.Identity.Name)
+	assert.True(t, me.Access)
+	assert.Equal(t, "org1", me.Owner)
+	assert.Equal(t, "repo1", me.Repo)
+	assert.Equal(t, core.PermissionRead, me.Permission)
+}
+
+func Test_AuthMeDeniedRepo(t *testing.T) {
+	server := newAuthTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon")})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "private: true\n")
+
+	loginThroughAuth(t, server, "/repo1/")
+	me := authMe(t, server, "https://org1.example.com/.pages/auth/me?path=/repo1/")
+	assert.True(t, me.Authenticated)
+	assert.False(t, me.Access)
+	assert.Empty(t, me.Repo)
+	assert.Empty(t, me.Permission)
+}
+
+func Test_AuthMePublicRepo(t *testing.T) {
+	server := newAuthTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon")})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "public")
+
+	me := authMe(t, server, "https://org1.example.com/.pages/auth/me?path=/repo1/")
+	assert.False(t, me.Authenticated)
+	assert.True(t, me.Access)
+	assert.Equal(t, "repo1", me.Repo)
+	assert.Empty(t, me.Permission)
+
+	// 未知页面与非法路径按站点根路径处理
+	me = authMe(t, server, "https://org1.example.com/.pages/auth/me?path=//evil.com")
+	assert.False(t, me.Access)
+	assert.Equal(t, "/.pages/auth/login?return_to=%2F", me.LoginURL)
+}
+
+func Test_AuthMeCORS(t *testing.T) {
+	server := newAuthTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+private: true
+security:
+  cors:
+    origins:
+      - https://app.example.com
+    credentials: true
+`)
+
+	req := httptest.NewRequest(http.MethodGet, "https://org1.example.com/.pages/auth/me?path=/repo1/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	_, resp, err := server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/.pages/auth/me?path=/repo1/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	_, resp, err = server.Do(req)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// 未配置跨域的页面沿用默认策略
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/.pages/auth/me", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	_, resp, err = server.Do(req)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func Test_AuthLoginAbsoluteReturnTo(t *testing.T) {
+	server := newAuthTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "private: true\n")
+
+	cases := map[string]string{
+		// 仅允许当前域名的 https 地址
+		"https://org1.example.com/repo1/?a=1": "/repo1/?a=1",
+		"https://ORG1.example.com/repo1/":     "/repo1/",
+		"http://org1.example.com/repo1/":      "/",
+		"https://org2.example.com/repo1/":     "/",
+		"https://evil.com/":                   "/",
+		"https://user@org1.example.com/":      "/",
+		"https://org1.example.com//evil.com":  "/",
+		"javascript:alert(1)":                 "/",
+		"/\\evil.com":                         "/",
+	}
+	for returnTo, expected := range cases {
+		_, resp, err := server.OpenFile("https://org1.example.com/.pages/auth/login?return_to=" + url.QueryEscape(returnTo))
+		require.NoError(t, err)
+		state := strings.TrimPrefix(resp.Header.Get("Location"), "/provider-login?state=")
+		_, resp, err = server.OpenFile("https://org1.example.com/.pages/auth/callback?code=ok&state=" + state)
+		require.NoError(t, err)
+		assert.Equal(t, expected, resp.Header.Get("Location"), returnTo)
+	}
+}
+
+func Test_SSOLoginAbsoluteReturnTo(t *testing.T) {
+	server := newSSOTestServer(t)
+	defer server.Close()
+
+	// 不按返回地址为其他域名签发票据，需在别名域名发起登录
+	location := redirectTo(t, server, "https://pages.example.com/.pages/auth/login?return_to="+url.QueryEscape("https://docs.corp.com/guide/"))
+	assert.True(t, strings.HasPrefix(location, "/provider-login?state="), location)
+	location = redirectTo(t, server, "https://docs.corp.com/.pages/auth/login?return_to="+url.QueryEscape("https://docs.corp.com/guide/"))
+	target, err := url.Parse(location)
+	require.NoError(t, err)
+	assert.Equal(t, "pages.example.com", target.Host)
+	assert.Equal(t, core.AuthPathSSO, target.Path)
+	assert.Equal(t, "docs.corp.com", target.Query().Get("host"))
+	assert.Equal(t, "/guide/", target.Query().Get("return_to"))
+
+	location = redirectTo(t, server, "https://pages.example.com/.pages/auth/login?return_to="+url.QueryEscape("https://evil.com/"))
+	assert.True(t, strings.HasPrefix(location, "/provider-login?state="), location)
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+// sessionAuthProvider 每次登录返回新的会话并统计授权检查次数
+type sessionAuthProvider struct {
+	fakeAuthProvider
+	expireAt  time.Time
+	authCalls int
+}
+
+func (p *sessionAuthProvider) HandleCallback(context.Context, *http.Request) (*core.AuthSession, error) {
+	return &core.AuthSession{
+		Identity: core.AuthIdentity{Subject: "u1", Name: "dragon"},
+		ExpireAt: p.expireAt,
+	}, nil
+}
+
+func (p *sessionAuthProvider) AuthorizeRepo(context.Context, *core.AuthSession, string, string) (bool, error) {
+	p.authCalls++
+	return true, nil
+}
+
+func newSessionTestServer(t *testing.T, provider core.AuthProvider, config core.AuthServiceConfig) (*testcore.TestServer, *core.AuthService) {
+	t.Helper()
+	store, err := kv.NewMemory("")
+	require.NoError(t, err)
+	config.CookieName = "test_session"
+	auth := core.NewAuthService(provider, store, config)
+	server := testcore.NewTestServerOptions("example.com", pkg.WithAuth(auth))
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "private: true\n")
+	return server, auth
+}
+
+func listSessions(t *testing.T, server *testcore.TestServer) []core.SessionInfo {
+	t.Helper()
+	data, resp, err := server.OpenFile("https://org1.example.com/.pages/auth/sessions")
+	require.NoError(t, err)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	var result struct {
+		Sessions []core.SessionInfo `json:"sessions"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	return result.Sessions
+}
+
+func Test_SessionSlidingRenewal(t *testing.T) {
+	provider := &sessionAuthProvider{expireAt: time.Now().Add(time.Hour)}
+	server, _ := newSessionTestServer(t, provider, core.AuthServiceConfig{
+		SessionTTL:    4 * time.Hour,
+		SessionMaxTTL: 2 * time.Hour,
+	})
+	defer server.Close()
+
+	loginThroughAuth(t, server, "/repo1/")
+	// 剩余有效期不足一半时续期，且不超过最长有效期
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 1)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), cookies[0].Expires, time.Minute)
+
+	sessions := listSessions(t, server)
+	require.Len(t, sessions, 1)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), sessions[0].ExpireAt, time.Minute)
+
+	// 已续期到上限后不再下发 Cookie
+	_, resp, err = server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Empty(t, resp.Cookies())
+}
+
+func Test_SessionListAndRevoke(t *testing.T) {
+	provider := &sessionAuthProvider{}
+	server, _ := newSessionTestServer(t, provider, core.AuthServiceConfig{})
+	defer server.Close()
+
+	_, resp, _ := server.OpenFile("https://org1.example.com/.pages/auth/sessions")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	loginThroughAuth(t, server, "/repo1/")
+	loginThroughAuth(t, server, "/repo1/")
+	sessions := listSessions(t, server)
+	require.Len(t, sessions, 2)
+	var current, other core.SessionInfo
+	for _, sess := range sessions {
+		if sess.Current {
+			current = sess
+		} else {
+			other = sess
+		}
+	}
+	require.NotEmpty(t, current.ID)
+	require.NotEmpty(t, other.ID)
+	assert.Equal(t, "org1.example.com", current.Host)
+
+	_, resp, err := server.OpenRequest(http.MethodDelete, "https://org1.example.com/.pages/auth/sessions?id="+other.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	sessions = listSessions(t, server)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, current.ID, sessions[0].ID)
+
+	_, resp, _ = server.OpenRequest(http.MethodDelete, "https://org1.example.com/.pages/auth/sessions?id="+other.ID, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// 撤销当前会话后需要重新登录
+	_, resp, err = server.OpenRequest(http.MethodDelete, "https://org1.example.com/.pages/auth/sessions?id="+current.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	_, resp, err = server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func Test_SessionRevokeSubject(t *testing.T) {
+	provider := &sessionAuthProvider{}
+	server, auth := newSessionTestServer(t, provider, core.AuthServiceConfig{AuthzCacheTTL: time.Hour})
+	defer server.Close()
+
+	loginThroughAuth(t, server, "/repo1/")
+	for range 2 {
+		data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+		require.NoError(t, err)
+		assert.Equal(t, "private", string(data))
+	}
+	assert.Equal(t, 1, provider.authCalls)
+
+	require.NoError(t, auth.RevokeSubject(context.Background(), "u1"))
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	// 授权缓存随会话一并清理
+	loginThroughAuth(t, server, "/repo1/")
+	_, _, err = server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, 2, provider.authCalls)
+}
@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+func newShareTestServer(t *testing.T, provider *fakeAuthProvider, secret string) *testcore.TestServer {
+	t.Helper()
+	store, err := kv.NewMemory("")
+	require.NoError(t, err)
+	server := testcore.NewTestServerOptions("example.com", pkg.WithAuth(core.NewAuthService(provider, store, core.AuthServiceConfig{
+		CookieName: "test_session",
+		Secret:     []byte(secret),
+	})))
+	server.AddFile("org1/repo1/gh-pages/index.html", "root")
+	server.AddFile("org1/repo1/gh-pages/secret.html", "secret")
+	server.AddFile("org1/repo1/gh-pages/docs/index.html", "docs")
+	server.AddFile("org1/repo1/gh-pages/docs/a.html", "doc a")
+	server.AddFile("org1/repo1/gh-pages/share.js", `
+serve(function() {
+  return Response.json(page.auth.share("docs/a.html", { ttl: 60 }))
+})
+`)
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+private: true
+routes:
+- path: "api/share"
+  js:
+    exec: "share.js"
+`)
+	return server
+}
+
+// createShare 调用分享接口并返回链接
+func createShare(t *testing.T, server *testcore.TestServer, query string) (string, *http.Response) {
+	t.Helper()
+	data, resp, _ := server.OpenRequest(http.MethodPost, "https://org1.example.com/.pages/auth/share?"+query, nil)
+	if resp.StatusCode != http.StatusOK {
+		return "", resp
+	}
+	var result struct {
+		URL string `json:"url"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	return result.URL, resp
+}
+
+// openShareLink 打开分享链接，校验跳转到去除令牌的地址后跟随跳转
+func openShareLink(t *testing.T, server *testcore.TestServer, link string) ([]byte, *http.Response, error) {
+	t.Helper()
+	_, resp, err := server.OpenFile(link)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	assert.Equal(t, "no-referrer", resp.Header.Get("Referrer-Policy"))
+	location := resp.Header.Get("Location")
+	assert.NotContains(t, location, core.ShareQueryKey)
+	linkURL, err := url.Parse(link)
+	require.NoError(t, err)
+	return server.OpenFile(linkURL.Scheme + "://" + linkURL.Host + location)
+}
+
+func Test_ShareLinkSubtree(t *testing.T) {
+	server := newShareTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true}, "test-secret")
+	defer server.Close()
+
+	_, resp := createShare(t, server, "path=/repo1/docs/")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	loginThroughAuth(t, server, "/repo1/")
+	link, resp := createShare(t, server, "path=/repo1/docs/&ttl=1h")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(link, "https://org1.example.com/repo1/docs/?"+core.ShareQueryKey+"="), link)
+	_, resp = createShare(t, server, "path=/repo1/docs/&ttl=1000h")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, resp, _ = server.OpenFile("https://org1.example.com/.pages/auth/share?path=/repo1/docs/")
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// 未登录的访客凭链接访问，随后通过 Cookie 访问子路径
+	server.ClearCookies()
+	_, resp, err := server.OpenFile(link + "&lang=en")
+	require.NoError(t, err)
+	assert.Equal(t, "/repo1/docs", resp.Cookies()[0].Path)
+	assert.Equal(t, "/repo1/docs/?lang=en", resp.Header.Get("Location"))
+	server.ClearCookies()
+	data, _, err := openShareLink(t, server, link)
+	require.NoError(t, err)
+	assert.Equal(t, "docs", string(data))
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/docs/a.html")
+	require.NoError(t, err)
+	assert.Equal(t, "doc a", string(data))
+
+	// 分享范围之外仍需登录
+	for _, target := range []string{"/repo1/secret.html", "/repo1/docs/../secret.html", "/repo1/docsx/"} {
+		_, resp, err = server.OpenFile("https://org1.example.com" + target)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusFound, resp.StatusCode, target)
+	}
+	token := strings.SplitN(link, "=", 2)[1]
+	_, resp, err = server.OpenFile("https://org1.example.com/repo1/secret.html?" + core.ShareQueryKey + "=" + token)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	// 篡改的令牌无效
+	server.ClearCookies()
+	_, resp, err = server.OpenFile(link + "x")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func Test_ShareLinkIPBinding(t *testing.T) {
+	server := newShareTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true}, "test-secret")
+	defer server.Close()
+	loginThroughAuth(t, server, "/repo1/")
+
+	// httptest 请求的客户端地址为 192.0.2.1
+	allowed, resp := createShare(t, server, "path=/repo1/secret.html&ip=192.0.2.0/24")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	denied, resp := createShare(t, server, "path=/repo1/secret.html&ip="+url.QueryEscape("198.51.100.7"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, resp = createShare(t, server, "path=/repo1/secret.html&ip=invalid")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	server.ClearCookies()
+	data, _, err := openShareLink(t, server, allowed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+	server.ClearCookies()
+	_, resp, err = server.OpenFile(denied)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func Test_ShareLinkRequiresAccess(t *testing.T) {
+	server := newShareTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon")}, "test-secret")
+	defer server.Close()
+	loginThroughAuth(t, server, "/repo1/")
+
+	_, resp := createShare(t, server, "path=/repo1/docs/")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	_, resp = createShare(t, server, "path=/repo9/")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func Test_ShareLinkRequiresSecret(t *testing.T) {
+	server := newShareTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true}, "")
+	defer server.Close()
+	loginThroughAuth(t, server, "/repo1/")
+
+	_, resp := createShare(t, server, "path=/repo1/docs/")
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+}
+
+func Test_GoJa_ShareLink(t *testing.T) {
+	server := newShareTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true}, "test-secret")
+	defer server.Close()
+	loginThroughAuth(t, server, "/repo1/")
+
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/api/share")
+	require.NoError(t, err)
+	var result struct {
+		URL      string `json:"url"`
+		ExpireAt string `json:"expireAt"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	assert.True(t, strings.HasPrefix(result.URL, "https://org1.example.com/repo1/docs/a.html?"), result.URL)
+	assert.NotEmpty(t, result.ExpireAt)
+
+	server.ClearCookies()
+	data, _, err = openShareLink(t, server, result.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "doc a", string(data))
+
+	// 凭分享链接访问的请求不能再签发链接
+	server.ClearCookies()
+	loginThroughAuth(t, server, "/repo1/")
+	link, resp := createShare(t, server, "path=/repo1/")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	server.ClearCookies()
+	_, _, err = openShareLink(t, server, link)
+	require.NoError(t, err)
+	_, resp, err = server.OpenFile("https://org1.example.com/repo1/api/share")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+// privateAuthProvider 仅在会话携带提供方数据时授权
+type privateAuthProvider struct {
+	fakeAuthProvider
+	seen []string
+}
+
+func (p *privateAuthProvider) AuthorizeRepo(_ context.Context, sess *core.AuthSession, _, _ string) (bool, error) {
+	p.seen = append(p.seen, string(sess.Private))
+	return string(sess.Private) == `"provider-token"`, nil
+}
+
+func newSSOTestServer(t *testing.T) *testcore.TestServer {
+	t.Helper()
+	session := authSession("u1", "dragon")
+	session.Private = []byte(`"provider-token"`)
+	return newSSOTestServerWith(t, &fakeAuthProvider{session: session, authorized: true})
+}
+
+func newSSOTestServerWith(t *testing.T, provider core.AuthProvider) *testcore.TestServer {
+	t.Helper()
+	store, err := kv.NewMemory("")
+	require.NoError(t, err)
+	server, _ := newSSOTestServerConfig(t, provider, store, core.AuthServiceConfig{})
+	return server
+}
+
+// newSSOTestServerConfig 在指定配置上启用统一登录域名
+func newSSOTestServerConfig(t *testing.T, provider core.AuthProvider, store kv.KV, config core.AuthServiceConfig) (*testcore.TestServer, *core.AuthService) {
+	t.Helper()
+	config.CookieName = "test_session"
+	config.CookieDomain = "example.com"
+	config.Secret = []byte("test-secret")
+	config.SSOHost = "pages.example.com"
+	auth := core.NewAuthService(provider, store, config)
+	server := testcore.NewTestServerOptions("example.com", pkg.WithAuth(auth))
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+private: true
+alias:
+  - docs.corp.com
+  - wiki.corp.com
+`)
+	// 首次访问时绑定别名
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	return server, auth
+}
+
+// redirectTo 请求地址并返回 302 跳转目标
+func redirectTo(t *testing.T, server *testcore.TestServer, target string) string {
+	t.Helper()
+	_, resp, err := server.OpenFile(target)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, resp.StatusCode, target)
+	return resp.Header.Get("Location")
+}
+
+// ssoLogin 从别名域名发起登录，返回统一登录域名签发的票据地址
+func ssoLogin(t *testing.T, server *testcore.TestServer, host string) string {
+	t.Helper()
+	location := redirectTo(t, server, "https://"+host+"/")
+	assert.Equal(t, "/.pages/auth/login?return_to=%2F", location)
+	location = redirectTo(t, server, "https://"+host+location)
+	require.True(t, strings.HasPrefix(location, "https://pages.example.com/.pages/auth/sso?"), location)
+	data, resp, err := server.OpenFile(location)
+	require.NoError(t, err)
+	if resp.StatusCode == http.StatusFound {
+		// 统一登录域名尚未登录
+		location = redirectTo(t, server, "https://pages.example.com"+resp.Header.Get("Location"))
+		state := strings.TrimPrefix(location, "/provider-login?state=")
+		location = "https://pages.example.com" + redirectTo(t, server, "https://pages.example.com/.pages/auth/callback?code=ok&state="+state)
+		data, resp, err = server.OpenFile(location)
+		require.NoError(t, err)
+	}
+	location = confirmSSO(t, server, location, string(data))
+	require.True(t, strings.HasPrefix(location, "https://"+host+"/.pages/auth/sso?ticket="), location)
+	return location
+}
+
+var ssoConfirmPattern = regexp.MustCompile(`name="confirm" value="([^"]+)"`)
+
+// confirmSSO 在统一登录域名的确认页提交表单，返回签发的票据地址
+func confirmSSO(t *testing.T, server *testcore.TestServer, location, page string) string {
+	t.Helper()
+	match := ssoConfirmPattern.FindStringSubmatch(page)
+	require.Len(t, match, 2, page)
+	req := httptest.NewRequest(http.MethodPost, location, strings.NewReader(url.Values{"confirm": {match[1]}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, resp, err := server.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	return resp.Header.Get("Location")
+}
+
+func Test_SSOAliasLogin(t *testing.T) {
+	server := newSSOTestServer(t)
+	defer server.Close()
+
+	ticketURL := ssoLogin(t, server, "docs.corp.com")
+	_, resp, err := server.OpenFile(ticketURL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/", resp.Header.Get("Location"))
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 1)
+	assert.Empty(t, cookies[0].Domain)
+
+	data, _, err := server.OpenFile("https://docs.corp.com/")
+	require.NoError(t, err)
+	assert.Equal(t, "private", string(data))
+
+	// 票据仅可使用一次
+	_, resp, _ = server.OpenFile(ticketURL)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 已登录时直接签发票据，票据与目标域名绑定
+	ticketURL = ssoLogin(t, server, "wiki.corp.com")
+	parsed, err := url.Parse(ticketURL)
+	require.NoError(t, err)
+	_, resp, _ = server.OpenFile("https://docs.corp.com" + parsed.RequestURI())
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	_, resp, err = server.OpenFile(ticketURL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func Test_SSOLogoutPropagates(t *testing.T) {
+	server := newSSOTestServer(t)
+	defer server.Close()
+	_, _, err := server.OpenFile(ssoLogin(t, server, "docs.corp.com"))
+	require.NoError(t, err)
+	_, _, err = server.OpenFile(ssoLogin(t, server, "wiki.corp.com"))
+	require.NoError(t, err)
+
+	_, resp, err := server.OpenRequest(http.MethodPost, "https://docs.corp.com/.pages/auth/logout", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	for _, host := range []string{"docs.corp.com", "wiki.corp.com"} {
+		assert.Equal(t, "/.pages/auth/login?return_to=%2F", redirectTo(t, server, "https://"+host+"/"))
+	}
+	// 统一登录会话同样被注销
+	location := redirectTo(t, server, "https://pages.example.com/.pages/auth/sso?host=wiki.corp.com&return_to=%2F")
+	assert.True(t, strings.HasPrefix(location, "/.pages/auth/login?"), location)
+}
+
+func Test_SSORejectsUnknownHost(t *testing.T) {
+	server := newSSOTestServer(t)
+	defer server.Close()
+
+	_, resp, _ := server.OpenFile("https://pages.example.com/.pages/auth/sso?host=evil.example.net&return_to=%2F")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	// 票据只能在统一登录域名签发
+	_, resp, _ = server.OpenFile("https://docs.corp.com/.pages/auth/sso?host=docs.corp.com")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	_, resp, _ = server.OpenFile("https://docs.corp.com/.pages/auth/sso?ticket=forged.signature")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func Test_SSORequiresConfirmation(t *testing.T) {
+	server := newSSOTestServer(t)
+	defer server.Close()
+	server.AddFile("org1/public/gh-pages/index.html", "public")
+	server.AddFile("org1/public/gh-pages/.pages.yaml", "alias:\n  - blog.corp.com\n")
+	_, _, err := server.OpenFile("https://org1.example.com/public/")
+	require.NoError(t, err)
+	_, _, err = server.OpenFile(ssoLogin(t, server, "docs.corp.com"))
+	require.NoError(t, err)
+
+	// 已登录时仍需确认，GET 请求不会签发票据
+	_, resp, err := server.OpenFile("https://pages.example.com/.pages/auth/sso?host=wiki.corp.com&return_to=%2F")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	req := httptest.NewRequest(http.MethodPost, "https://pages.example.com/.pages/auth/sso?host=wiki.corp.com&return_to=%2F",
+		strings.NewReader("confirm=forged"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// 不需要登录的页面不签发票据
+	_, resp, _ = server.OpenFile("https://pages.example.com/.pages/auth/sso?host=blog.corp.com&return_to=%2F")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func Test_SSOSessionBoundToHost(t *testing.T) {
+	session := authSession("u1", "dragon")
+	session.Private = []byte(`"provider-token"`)
+	provider := &privateAuthProvider{fakeAuthProvider: fakeAuthProvider{session: session}}
+	server := newSSOTestServerWith(t, provider)
+	defer server.Close()
+	_, resp, err := server.OpenFile(ssoLogin(t, server, "docs.corp.com"))
+	require.NoError(t, err)
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 1)
+
+	// 派生会话的 Cookie 被转交到其他域名时无效
+	server.ClearCookies()
+	req := httptest.NewRequest(http.MethodGet, "https://wiki.corp.com/", nil)
+	req.AddCookie(&http.Cookie{Name: cookies[0].Name, Value: cookies[0].Value})
+	_, resp, err = server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/.pages/auth/login?return_to=%2F", resp.Header.Get("Location"))
+
+	req = httptest.NewRequest(http.MethodGet, "https://docs.corp.com/", nil)
+	req.AddCookie(&http.Cookie{Name: cookies[0].Name, Value: cookies[0].Value})
+	data, _, err := server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, "private", string(data))
+	// 派生会话不保存提供方数据，授权时使用统一登录会话的数据
+	assert.Equal(t, []string{`"provider-token"`}, provider.seen)
+}
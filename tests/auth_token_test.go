@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+type tokenAuthProvider struct {
+	fakeAuthProvider
+	tokens map[string]string
+	calls  int
+}
+
+func (p *tokenAuthProvider) AuthenticateToken(_ context.Context, token string) (*core.AuthSession, bool, error) {
+	p.calls++
+	name, ok := p.tokens[token]
+	if !ok {
+		return nil, false, nil
+	}
+	return &core.AuthSession{Identity: core.AuthIdentity{Subject: "token-" + name, Name: name}}, true, nil
+}
+
+const basicToken = "0123456789abcdef0123456789abcdef01234567"
+
+func Test_TokenAuthPrivateRepo(t *testing.T) {
+	provider := &tokenAuthProvider{
+		fakeAuthProvider: fakeAuthProvider{authorized: true},
+		tokens:           map[string]string{"pat-1": "ci", basicToken: "ci"},
+	}
+	server := newAuthTestServer(t, provider)
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "private: true\n")
+
+	req, err := http.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer pat-1")
+	data, resp, err := server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, "private", string(data))
+	assert.Empty(t, resp.Header.Values("Set-Cookie"))
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "private")
+
+	// Basic 认证以令牌为密码
+	req, err = http.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("ci", basicToken)
+	data, _, err = server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, "private", string(data))
+	assert.Equal(t, 2, provider.calls)
+
+	// 普通 Basic 凭据与仅含用户名的 Basic 认证不作为令牌校验
+	for _, credentials := range [][2]string{{"alice", "hunter2"}, {basicToken, ""}, {"ci", "pat-1"}} {
+		req, err = http.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+		require.NoError(t, err)
+		req.SetBasicAuth(credentials[0], credentials[1])
+		_, resp, _ = server.Do(req)
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, 2, provider.calls)
+
+	for range 2 {
+		req, err = http.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "token bad")
+		_, resp, _ = server.Do(req)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, `Bearer realm="gitea-pages"`, resp.Header.Get("WWW-Authenticate"))
+		assert.Empty(t, resp.Header.Values("Set-Cookie"))
+	}
+	// 无效令牌不缓存，每次重新校验
+	assert.Equal(t, 4, provider.calls)
+}
+
+func Test_TokenAuthFailureLimit(t *testing.T) {
+	provider := &tokenAuthProvider{
+		fakeAuthProvider: fakeAuthProvider{authorized: true},
+		tokens:           map[string]string{"pat-1": "ci"},
+	}
+	server := newAuthTestServer(t, provider)
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "private: true\n")
+
+	open := func(token, remote string) int {
+		req, err := http.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = remote
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, resp, _ := server.Do(req)
+		return resp.StatusCode
+	}
+	for i := range 10 {
+		assert.Equal(t, http.StatusUnauthorized, open(fmt.Sprintf("bad-%d", i), "192.0.2.1:1234"))
+	}
+	assert.Equal(t, 10, provider.calls)
+
+	// 超出失败次数后不再向认证服务校验
+	assert.Equal(t, http.StatusUnauthorized, open("bad-10", "192.0.2.1:1234"))
+	assert.Equal(t, http.StatusUnauthorized, open("pat-1", "192.0.2.1:1234"))
+	assert.Equal(t, 10, provider.calls)
+
+	// 其他客户端不受影响
+	assert.Equal(t, http.StatusOK, open("pat-1", "192.0.2.2:1234"))
+	assert.Equal(t, 11, provider.calls)
+}
+
+func Test_TokenAuthForbidden(t *testing.T) {
+	provider := &tokenAuthProvider{
+		fakeAuthProvider: fakeAuthProvider{authorized: false},
+		tokens:           map[string]string{"pat-1": "ci"},
+	}
+	server := newAuthTestServer(t, provider)
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "private")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "private: true\n")
+
+	req, err := http.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer pat-1")
+	_, resp, _ := server.Do(req)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func Test_TokenAuthIgnoredForPublicRepo(t *testing.T) {
+	provider := &tokenAuthProvider{fakeAuthProvider: fakeAuthProvider{authorized: true}}
+	server := newAuthTestServer(t, provider)
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "public")
+
+	req, err := http.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer unknown")
+	data, _, err := server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, "public", string(data))
+	assert.Equal(t, 0, provider.calls)
+}
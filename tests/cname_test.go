@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,7 +12,8 @@ import (
 
 func TestCNAMERegex(t *testing.T) {
 	db, _ := kv.NewMemory("")
-	meta := core.NewServerMeta(nil, nil, "example.com", nil, db, 0, 0, 0, nil, nil)
+	meta, err := core.NewServerMeta(nil, nil, "example.com", nil, db, 0, 0, 0, nil, nil)
+	assert.NoError(t, err)
 
 	tests := []struct {
 		domain string
@@ -24,6 +26,15 @@ func TestCNAMERegex(t *testing.T) {
 		{"-start.com", false},
 		{"end-.com", false},
 		{"a.com-too-long-tld-xxxxxxxxxxxxxxxxxxx", false},
+		{"*.docs.example.org", true},
+		{"*.com", false},
+		{"*.co.uk", false},
+		{"*.github.io", false},
+		{"*.example.co.uk", true},
+		{"a.*.example.org", false},
+		{"bücher.de", true},
+		{"例子.中国", true},
+		{"docs.example.com", false},
 	}
 
 	for _, tt := range tests {
@@ -32,6 +43,64 @@ func TestCNAMERegex(t *testing.T) {
 	}
 }
 
+func TestAliasCheckNormalize(t *testing.T) {
+	db, _ := kv.NewMemory("")
+	meta, err := core.NewServerMeta(nil, nil, "example.com", nil, db, 0, 0, 0, nil, nil)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"Docs.Example.ORG", "docs.example.org"},
+		{"bücher.de", "xn--bcher-kva.de"},
+		{"*.Bücher.de", "*.xn--bcher-kva.de"},
+		{"例子.中国", "xn--fsqu00a.xn--fiqs8s"},
+	}
+
+	for _, tt := range tests {
+		got, ok := meta.AliasCheck(tt.domain)
+		assert.True(t, ok, "Testing domain: %s", tt.domain)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestAliasWildcardMatch(t *testing.T) {
+	db, _ := kv.NewMemory("")
+	alias := core.NewDomainAlias(db)
+	ctx := context.Background()
+
+	assert.NoError(t, alias.Bind(ctx, []string{"*.docs.example.org"}, "owner1", "repo1"))
+	assert.NoError(t, alias.Bind(ctx, []string{"*.v2.docs.example.org", "exact.docs.example.org"}, "owner1", "repo2"))
+
+	a, sub, err := alias.Match(ctx, "team.docs.example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "repo1", a.Repo)
+	assert.Equal(t, "team", sub)
+
+	a, sub, err = alias.Match(ctx, "a.b.docs.example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "repo1", a.Repo)
+	assert.Equal(t, "a.b", sub)
+
+	a, sub, err = alias.Match(ctx, "beta.v2.docs.example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "repo2", a.Repo)
+	assert.Equal(t, "beta", sub)
+
+	a, sub, err = alias.Match(ctx, "exact.docs.example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "repo2", a.Repo)
+	assert.Empty(t, sub)
+
+	_, _, err = alias.Match(ctx, "docs.example.org")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.True(t, core.MatchAliasDomain([]string{"*.docs.example.org"}, "x.docs.example.org"))
+	assert.False(t, core.MatchAliasDomain([]string{"*.docs.example.org"}, "docs.example.org"))
+	assert.False(t, core.MatchAliasDomain([]string{"*.docs.example.org"}, "xdocs.example.org"))
+}
+
 func TestAliasBindCAS(t *testing.T) {
 	db, _ := kv.NewMemory("")
 
@@ -6,10 +6,11 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
-	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -20,10 +21,9 @@ import (
 )
 
 type TestServer struct {
-	server    *pkg.Server
-	dummy     *ProviderDummy
-	cookiesMu sync.Mutex
-	cookies   map[string]*http.Cookie
+	server  *pkg.Server
+	dummy   *ProviderDummy
+	cookies http.CookieJar
 }
 
 func NewDefaultTestServer() *TestServer {
@@ -68,15 +68,21 @@ func NewTestServerWithKVOptions(domain string, db, userDB kv.KV, opts ...pkg.Ser
 				}
 			}),
 			pkg.WithFilterConfig(make(map[string]map[string]any)),
+			// 测试环境不查询真实 DNS
+			pkg.WithCNAMEResolver(func(context.Context, string) (string, error) {
+				return "", os.ErrNotExist
+			}),
 		}, opts...)...,
 	)
 	if err != nil {
 		panic(err)
 	}
+	// 按域名与路径保存 Cookie，模拟浏览器的 Cookie 隔离
+	jar, _ := cookiejar.New(nil)
 	return &TestServer{
 		dummy:   dummy,
 		server:  server,
-		cookies: map[string]*http.Cookie{},
+		cookies: jar,
 	}
 }
 
@@ -108,26 +114,13 @@ func (t *TestServer) OpenRequestWithContext(ctx context.Context, method, url str
 
 func (t *TestServer) Do(req *http.Request) ([]byte, *http.Response, error) {
 	recorder := httptest.NewRecorder()
-	t.cookiesMu.Lock()
-	cookies := make([]*http.Cookie, 0, len(t.cookies))
-	for _, cookie := range t.cookies {
-		cookies = append(cookies, cookie)
-	}
-	t.cookiesMu.Unlock()
-	for _, cookie := range cookies {
+	requestURL := t.requestURL(req)
+	for _, cookie := range t.cookies.Cookies(requestURL) {
 		req.AddCookie(cookie)
 	}
 	t.server.ServeHTTP(recorder, req)
 	response := recorder.Result()
-	t.cookiesMu.Lock()
-	for _, cookie := range response.Cookies() {
-		if cookie.MaxAge < 0 || cookie.Value == "" {
-			delete(t.cookies, cookie.Name)
-			continue
-		}
-		t.cookies[cookie.Name] = cookie
-	}
-	t.cookiesMu.Unlock()
+	t.cookies.SetCookies(requestURL, response.Cookies())
 	if response.Body != nil {
 		defer response.Body.Close()
 	}
@@ -138,6 +131,24 @@ func (t *TestServer) Do(req *http.Request) ([]byte, *http.Response, error) {
 	return all, response, nil
 }
 
+// ClearCookies 清空保存的 Cookie，模拟另一位访客
+func (t *TestServer) ClearCookies() {
+	t.cookies, _ = cookiejar.New(nil)
+}
+
+// requestURL 返回请求的完整地址，用于匹配 Cookie
+func (t *TestServer) requestURL(req *http.Request) *url.URL {
+	result := *req.URL
+	result.Host = req.Host
+	if result.Scheme == "" {
+		result.Scheme = "https"
+		if req.TLS == nil {
+			result.Scheme = "http"
+		}
+	}
+	return &result
+}
+
 func (t *TestServer) Close() error {
 	return nil
 }
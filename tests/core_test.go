@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.d7z.net/gitea-pages/pkg"
 	"gopkg.d7z.net/gitea-pages/tests/core"
 )
 
@@ -119,3 +120,85 @@ func Test_fail_back(t *testing.T) {
 		assert.Equal(t, "hello world 2", string(data))
 	})
 }
+
+func Test_get_wildcard_alias(t *testing.T) {
+	server := core.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello {{ .Meta.Subdomain }}")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+alias:
+  - "*.docs.example.org"
+  - bücher.example.org
+routes:
+- path: index.html
+  template:
+`)
+	// 通配符别名不能作为跳转目标，使用第一个具体别名
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/")
+	assert.Equal(t, 302, resp.StatusCode)
+	assert.Equal(t, "https://xn--bcher-kva.example.org/", resp.Header.Get("Location"))
+
+	data, _, err := server.OpenFile("https://team.docs.example.org/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello team", string(data))
+
+	data, _, err = server.OpenFile("https://xn--bcher-kva.example.org/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello ", string(data))
+
+	_, resp, _ = server.OpenFile("https://docs.example.org/")
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
+func Test_get_repo_subdomain(t *testing.T) {
+	server := core.NewTestServerOptions("example.com", pkg.WithRepoSubdomain(true))
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello repo1")
+	server.AddFile("org1/repo1/gh-pages/docs/index.html", "hello docs")
+	server.AddFile("org1/org1.example.com/gh-pages/index.html", "hello default")
+	server.AddFile("org1/sub.site/gh-pages/index.html", "hello sub.site")
+
+	data, _, err := server.OpenFile("https://repo1.org1.example.com/docs/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello docs", string(data))
+
+	data, _, err = server.OpenFile("https://sub.site.org1.example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello sub.site", string(data))
+
+	// 未声明 hosting: subdomain 时路径模式仍可访问
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/docs/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello docs", string(data))
+
+	data, _, err = server.OpenFile("https://org1.example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello default", string(data))
+
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+hosting: subdomain
+`)
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/docs/?q=1")
+	assert.Equal(t, 301, resp.StatusCode)
+	assert.Equal(t, "https://repo1.org1.example.com/docs/?q=1", resp.Header.Get("Location"))
+
+	data, _, err = server.OpenFile("https://repo1.org1.example.com/docs/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello docs", string(data))
+}
+
+func Test_get_repo_subdomain_disabled(t *testing.T) {
+	server := core.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello repo1")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+hosting: subdomain
+`)
+
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello repo1", string(data))
+
+	_, resp, _ := server.OpenFile("https://repo1.org1.example.com/")
+	assert.Equal(t, 404, resp.StatusCode)
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.d7z.net/gitea-pages/pkg"
 	"gopkg.d7z.net/gitea-pages/pkg/core"
 	testcore "gopkg.d7z.net/gitea-pages/tests/core"
@@ -110,3 +111,80 @@ routes:
 	assert.Equal(t, "download body", string(data))
 	assert.Equal(t, "private, max-age=60", resp.Header.Get("Cache-Control"))
 }
+
+func Test_Filter_DirectCleanURLs(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/about.html", "about")
+	server.AddFile("org1/repo1/gh-pages/docs/index.html", "docs")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+clean_urls: true
+`)
+
+	data, resp, err := server.OpenFile("https://org1.example.com/repo1/about")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "about", string(data))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/about.html?a=1")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/repo1/about?a=1", resp.Header.Get("Location"))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/about/")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/repo1/about", resp.Header.Get("Location"))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/docs/index.html")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/repo1/docs/", resp.Header.Get("Location"))
+
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/docs/")
+	require.NoError(t, err)
+	assert.Equal(t, "docs", string(data))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func Test_Filter_DirectTrailingSlash(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/about.html", "about")
+	server.AddFile("org1/repo1/gh-pages/docs/index.html", "docs")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+clean_urls: true
+trailing_slash: always
+`)
+
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/about")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/repo1/about/", resp.Header.Get("Location"))
+
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/about/")
+	require.NoError(t, err)
+	assert.Equal(t, "about", string(data))
+
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+trailing_slash: never
+`)
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/docs")
+	require.NoError(t, err)
+	assert.Equal(t, "docs", string(data))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/docs/")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/repo1/docs", resp.Header.Get("Location"))
+
+	// 页面根路径不受影响
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, "home", string(data))
+
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+trailing_slash: sometimes
+`)
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
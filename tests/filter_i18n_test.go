@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func Test_I18nRedirect(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "root")
+	server.AddFile("org1/repo1/gh-pages/logo.png", "logo")
+	server.AddFile("org1/repo1/gh-pages/en/index.html", "en home")
+	server.AddFile("org1/repo1/gh-pages/en/guide.html", "en guide")
+	server.AddFile("org1/repo1/gh-pages/zh/index.html", "zh home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "**"
+  i18n:
+    locales: [en, zh, ja]
+    default: en
+`)
+
+	req := httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/?x=1", nil)
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	_, resp, _ := server.Do(req)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/repo1/zh/?x=1", resp.Header.Get("Location"))
+	assert.Contains(t, resp.Header.Values("Vary"), "Accept-Language, Cookie")
+
+	// Cookie 优先于 Accept-Language
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	req.Header.Set("Accept-Language", "zh")
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "ja"})
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, "/repo1/ja/", resp.Header.Get("Location"))
+
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	req.Header.Set("Accept-Language", "fr")
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, "/repo1/en/", resp.Header.Get("Location"))
+
+	// 语言目录外存在的文件原样提供，不存在的页面跳转到语言目录
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/logo.png")
+	require.NoError(t, err)
+	assert.Equal(t, "logo", string(data))
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/guide.html", nil)
+	req.Header.Set("Accept-Language", "zh")
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, "/repo1/zh/guide.html", resp.Header.Get("Location"))
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/myindex.html", nil)
+	req.Header.Set("Accept-Language", "zh")
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, "/repo1/zh/myindex.html", resp.Header.Get("Location"))
+
+	// 缺失的翻译回退到默认语言，且不改变记住的语言
+	data, resp, err = server.OpenFile("https://org1.example.com/repo1/zh/guide.html")
+	require.NoError(t, err)
+	assert.Equal(t, "en guide", string(data))
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "zh", cookies[0].Value)
+	assert.Equal(t, "/repo1/", cookies[0].Path)
+	assert.True(t, cookies[0].Secure)
+
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/zh/", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "zh"})
+	data, resp, err = server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, "zh home", string(data))
+	assert.Empty(t, resp.Cookies())
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/zh/missing.html")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func Test_I18nRewrite(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "root")
+	server.AddFile("org1/repo1/gh-pages/en/index.html", "en home")
+	server.AddFile("org1/repo1/gh-pages/ja/index.html", "ja home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "**"
+  i18n:
+    locales: [en, ja]
+    mode: rewrite
+`)
+
+	req := httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	req.Header.Set("Accept-Language", "ja-JP")
+	data, resp, err := server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ja home", string(data))
+	assert.Empty(t, resp.Cookies())
+}
@@ -26,3 +26,60 @@ routes:
 	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
 	assert.Equal(t, "https://target.example.com/legacy/docs/?q=1", resp.Header.Get("Location"))
 }
+
+func Test_Filter_RedirectCanonicalDomain(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+canonical: www.example.org
+alias:
+  - other.example.net
+  - "*.preview.example.org"
+`)
+
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/docs/?q=1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://www.example.org/docs/?q=1", resp.Header.Get("Location"))
+
+	_, resp, err = server.OpenFile("https://example.org/docs/page.html?q=1&b=2")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://www.example.org/docs/page.html?q=1&b=2", resp.Header.Get("Location"))
+
+	_, resp, err = server.OpenFile("https://other.example.net/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://www.example.org/", resp.Header.Get("Location"))
+
+	data, _, err := server.OpenFile("https://www.example.org/")
+	assert.NoError(t, err)
+	assert.Equal(t, "home", string(data))
+
+	data, _, err = server.OpenFile("https://pr-1.preview.example.org/")
+	assert.NoError(t, err)
+	assert.Equal(t, "home", string(data))
+}
+
+func Test_Filter_RedirectCanonicalApex(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+canonical: example.org
+`)
+
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+
+	_, resp, err = server.OpenFile("https://www.example.org/a?b=c")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://example.org/a?b=c", resp.Header.Get("Location"))
+
+	data, _, err := server.OpenFile("https://example.org/")
+	assert.NoError(t, err)
+	assert.Equal(t, "home", string(data))
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func Test_RewriteFilter(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/docs/v3/index.html", "v3 home")
+	server.AddFile("org1/repo1/gh-pages/docs/v3/guide.html", "v3 guide")
+	server.AddFile("org1/repo1/gh-pages/hello.js", `
+serve(function(request) {
+  return new Response("hello " + request.params.name)
+})
+`)
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "docs/latest/**"
+  rewrite:
+    target: docs/v3/:splat
+- path: "users/:name"
+  rewrite:
+    target: api/hello/:name
+- path: "api/hello/:name"
+  js:
+    exec: hello.js
+- path: "loop/**"
+  rewrite:
+    target: loop/:splat
+`)
+
+	data, resp, err := server.OpenFile("https://org1.example.com/repo1/docs/latest/guide.html")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "v3 guide", string(data))
+
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/docs/latest/")
+	require.NoError(t, err)
+	assert.Equal(t, "v3 home", string(data))
+
+	// 改写后重新匹配路由
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/users/alice")
+	require.NoError(t, err)
+	assert.Equal(t, "hello alice", string(data))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/docs/latest/missing.html")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/loop/a")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func Test_RewriteFilterInvalid(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "docs/**"
+  rewrite: {}
+`)
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
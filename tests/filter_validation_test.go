@@ -62,3 +62,64 @@ routes:
 	assert.NoError(t, err)
 	assert.Equal(t, "good repo", string(data))
 }
+
+func Test_PageConfigInvalidCanonicalReturnsClearError(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello world")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+canonical: "*.example.org"
+`)
+
+	_, resp, err := server.OpenRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func Test_PageConfigInvalidFilterParamsFailAtRefresh(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello world")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "old/**"
+  redirect:
+    targets: [new.example.org]
+    code: 200
+`)
+	server.AddFile("org1/repo2/gh-pages/index.html", "hello world")
+	server.AddFile("org1/repo2/gh-pages/.pages.yaml", `
+routes:
+- path: "spa/**"
+  failback: {}
+`)
+
+	// 未命中错误路由的请求同样返回配置错误
+	_, resp, err := server.OpenRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	_, resp, err = server.OpenRequest(http.MethodGet, "https://org1.example.com/repo2/", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func Test_PageConfigBlockWithoutCodeReturnsNotFound(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello world")
+	server.AddFile("org1/repo1/gh-pages/secret.html", "secret")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "secret.html"
+  block: {}
+`)
+
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/secret.html")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
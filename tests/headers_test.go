@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func Test_HeadersFileAndRoute(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/assets/app.js", "console.log(1)")
+	server.AddFile("org1/repo1/gh-pages/docs/index.html", "docs")
+	server.AddFile("org1/repo1/gh-pages/api.js", `
+serve(function(request) {
+  return new Response("ok", { headers: { "X-Api": "js" } })
+})
+`)
+	server.AddFile("org1/repo1/gh-pages/_headers", `
+/*
+  X-Frame-Options: SAMEORIGIN
+  Content-Security-Policy: default-src 'self'
+/assets/*
+  Cache-Control: public, max-age=31536000, immutable
+/docs
+  Link: </docs/style.css>; rel=preload; as=style
+  Link: </docs/app.js>; rel=preload; as=script
+`)
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "assets/**"
+  headers:
+    Cache-Control: public, max-age=60
+- path: "api"
+  headers:
+    X-Custom: [a, b]
+- path: "api"
+  js:
+    exec: api.js
+`)
+
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, "SAMEORIGIN", resp.Header.Get("X-Frame-Options"))
+	assert.Equal(t, "default-src 'self'", resp.Header.Get("Content-Security-Policy"))
+
+	// .pages.yaml 中的路由覆盖 _headers
+	_, resp, err = server.OpenFile("https://org1.example.com/repo1/assets/app.js")
+	require.NoError(t, err)
+	assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"))
+
+	_, resp, err = server.OpenFile("https://org1.example.com/repo1/docs/")
+	require.NoError(t, err)
+	assert.Len(t, resp.Header.Values("Link"), 2)
+
+	data, resp, err := server.OpenFile("https://org1.example.com/repo1/api")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(data))
+	assert.Equal(t, "js", resp.Header.Get("X-Api"))
+	assert.Equal(t, []string{"a", "b"}, resp.Header.Values("X-Custom"))
+	assert.Equal(t, "SAMEORIGIN", resp.Header.Get("X-Frame-Options"))
+
+	// 错误响应不使用自定义响应头
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/assets/missing.js")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.NotContains(t, resp.Header.Get("Cache-Control"), "immutable")
+}
+
+func Test_HeadersInvalid(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "**"
+  headers:
+    Set-Cookie: a=b
+`)
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.d7z.net/gitea-pages/pkg"
+	"gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func Test_OrgDomain(t *testing.T) {
+	server := core.NewTestServerOptions("example.com", pkg.WithCNAMEResolver(func(_ context.Context, host string) (string, error) {
+		switch host {
+		case "docs.corp.example":
+			return "corp.example.com.", nil
+		case "other.corp.example":
+			return "other.example.net.", nil
+		}
+		return "", os.ErrNotExist
+	}))
+	defer server.Close()
+	server.AddFile("corp/.pages/gh-pages/.pages.yaml", `
+domains:
+  - docs.corp.example
+`)
+	server.AddFile("corp/.pages/gh-pages/index.html", "config repo")
+	server.AddFile("corp/repo1/gh-pages/index.html", "hello repo1")
+	server.AddFile("corp/repo2/gh-pages/index.html", "hello repo2")
+	server.AddFile("corp/docs.corp.example/gh-pages/index.html", "hello default")
+
+	// CNAME 指向组织域名时首次访问即绑定，无需先经由基础域名访问
+	data, _, err := server.OpenFile("https://docs.corp.example/repo1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello repo1", string(data))
+	_, resp, _ := server.OpenFile("https://other.corp.example/repo1/")
+	assert.Equal(t, 404, resp.StatusCode)
+
+	data, _, err = server.OpenFile("https://corp.example.com/repo1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello repo1", string(data))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello repo1", string(data))
+	data, _, err = server.OpenFile("https://docs.corp.example/repo2/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello repo2", string(data))
+	data, _, err = server.OpenFile("https://docs.corp.example/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello default", string(data))
+
+	// 配置仓库本身不作为页面
+	_, resp, _ = server.OpenFile("https://docs.corp.example/.pages/")
+	assert.Equal(t, 404, resp.StatusCode)
+
+	// 移除域名后解除绑定
+	server.AddFile("corp/.pages/gh-pages/.pages.yaml", `
+domains: []
+`)
+	_, _, err = server.OpenFile("https://corp.example.com/repo2/")
+	assert.NoError(t, err)
+	_, resp, _ = server.OpenFile("https://docs.corp.example/repo1/")
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
+func Test_OrgDomainCNAMELookupCached(t *testing.T) {
+	var lookups atomic.Int32
+	server := core.NewTestServerOptions("example.com", pkg.WithCNAMEResolver(func(_ context.Context, host string) (string, error) {
+		lookups.Add(1)
+		if host == "docs.corp.example" {
+			return "corp.example.com.", nil
+		}
+		return "", os.ErrNotExist
+	}))
+	defer server.Close()
+	server.AddFile("corp/.pages/gh-pages/.pages.yaml", `
+domains:
+  - docs.corp.example
+`)
+	server.AddFile("corp/repo1/gh-pages/index.html", "hello repo1")
+
+	// 未绑定的域名并发访问只查询一次，查询失败的结果同样缓存
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, resp, _ := server.OpenFile("https://unknown.example.org/")
+			assert.Equal(t, 404, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), lookups.Load())
+
+	data, _, err := server.OpenFile("https://docs.corp.example/repo1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello repo1", string(data))
+	_, resp, _ := server.OpenFile("https://unknown.example.org/")
+	assert.Equal(t, 404, resp.StatusCode)
+	assert.Equal(t, int32(2), lookups.Load())
+}
+
+func Test_OrgDomainRepoAliasRedirect(t *testing.T) {
+	server := core.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("corp/.pages/gh-pages/.pages.yaml", `
+domains:
+  - docs.corp.example
+`)
+	server.AddFile("corp/repo1/gh-pages/index.html", "hello repo1")
+	server.AddFile("corp/repo1/gh-pages/.pages.yaml", `
+alias:
+  - repo1.corp.example
+`)
+
+	_, resp, _ := server.OpenFile("https://corp.example.com/repo1/")
+	assert.Equal(t, 302, resp.StatusCode)
+	_, resp, _ = server.OpenFile("https://docs.corp.example/repo1/a.html")
+	assert.Equal(t, 302, resp.StatusCode)
+	assert.Equal(t, "https://repo1.corp.example/a.html", resp.Header.Get("Location"))
+}
+
+func Test_OrgConfigRepoOption(t *testing.T) {
+	server := core.NewTestServerOptions("example.com", pkg.WithOrgConfigRepo("pages-config"))
+	defer server.Close()
+	server.AddFile("corp/pages-config/gh-pages/.pages.yaml", `
+domains:
+  - docs.corp.example
+`)
+	server.AddFile("corp/repo1/gh-pages/index.html", "hello repo1")
+
+	_, _, err := server.OpenFile("https://corp.example.com/repo1/")
+	assert.NoError(t, err)
+	data, _, err := server.OpenFile("https://docs.corp.example/repo1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello repo1", string(data))
+}
+
+func Test_OrgDefaults(t *testing.T) {
+	server := core.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("corp/.pages/gh-pages/.pages.yaml", `
+defaults:
+  security:
+    cors:
+      origins:
+        - https://app.corp.example
+    headers:
+      frame_options: DENY
+  block:
+    - "internal/**"
+`)
+	server.AddFile("corp/repo1/gh-pages/index.html", "hello repo1")
+	server.AddFile("corp/repo1/gh-pages/internal/secret.txt", "secret")
+	server.AddFile("corp/repo2/gh-pages/index.html", "hello repo2")
+	server.AddFile("corp/repo2/gh-pages/internal/secret.txt", "secret")
+	server.AddFile("corp/repo2/gh-pages/.pages.yaml", `
+security:
+  headers:
+    frame_options: SAMEORIGIN
+routes:
+- path: "internal/**"
+  direct:
+`)
+
+	req := httptest.NewRequest(http.MethodGet, "https://corp.example.com/repo1/", nil)
+	req.Header.Set("Origin", "https://app.corp.example")
+	_, resp, err := server.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.corp.example", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+
+	// 仓库显式字段覆盖组织默认值，未声明的字段继承
+	req = httptest.NewRequest(http.MethodGet, "https://corp.example.com/repo2/", nil)
+	req.Header.Set("Origin", "https://app.corp.example")
+	_, resp, err = server.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.corp.example", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "SAMEORIGIN", resp.Header.Get("X-Frame-Options"))
+
+	// 组织 block 路径无法被仓库覆盖
+	_, resp, _ = server.OpenFile("https://corp.example.com/repo1/internal/secret.txt")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	_, resp, _ = server.OpenFile("https://corp.example.com/repo2/internal/secret.txt")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// 其他组织不受影响
+	server.AddFile("other/repo1/gh-pages/internal/secret.txt", "secret")
+	server.AddFile("other/repo1/gh-pages/index.html", "hello")
+	data, _, err := server.OpenFile("https://other.example.com/repo1/internal/secret.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+}
@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+func passwordHash(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(hash)
+}
+
+// submitPassword 跟随跳转到口令表单并提交，返回提交结果
+func submitPassword(t *testing.T, server *testcore.TestServer, target string, form url.Values) *http.Response {
+	t.Helper()
+	_, resp, err := server.OpenFile(target)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location := resp.Header.Get("Location")
+	require.True(t, strings.HasPrefix(location, "/.pages/password?"), location)
+
+	data, resp, err := server.OpenFile("https://org1.example.com" + location)
+	require.NoError(t, err)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	assert.Contains(t, string(data), `name="password"`)
+
+	req := httptest.NewRequest(http.MethodPost, "https://org1.example.com"+location, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, resp, _ = server.Do(req)
+	return resp
+}
+
+func Test_PasswordProtectedSite(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	hash := passwordHash(t, "s3cret")
+	server.AddFile("org1/repo1/gh-pages/index.html", "draft")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "password:\n  hash: '%s'\n", hash)
+	server.AddFile("org1/repo2/gh-pages/index.html", "other draft")
+	server.AddFile("org1/repo2/gh-pages/.pages.yaml", "password:\n  hash: '%s'\n", hash)
+
+	resp := submitPassword(t, server, "https://org1.example.com/repo1/?a=1", url.Values{"password": {"wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = submitPassword(t, server, "https://org1.example.com/repo1/?a=1", url.Values{"password": {"s3cret"}})
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	assert.Equal(t, "/repo1/?a=1", resp.Header.Get("Location"))
+	cookie := resp.Cookies()[0]
+	assert.Equal(t, "/repo1", cookie.Path)
+	assert.True(t, cookie.HttpOnly)
+
+	data, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, "draft", string(data))
+	assert.Equal(t, "private, max-age=60", resp.Header.Get("Cache-Control"))
+
+	// Cookie 限定于仓库，同一口令的其他仓库仍需输入
+	_, resp, err = server.OpenFile("https://org1.example.com/repo2/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	// 伪造的 Cookie 无效
+	req := httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo2/", nil)
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	_, resp, err = server.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	// 非 GET 请求不跳转
+	_, resp, err = server.OpenRequest(http.MethodPost, "https://org1.example.com/repo2/", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func Test_PasswordRouteHtpasswd(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "public")
+	server.AddFile("org1/repo1/gh-pages/drafts/index.html", "draft")
+	server.AddFile("org1/repo1/gh-pages/.htpasswd", "alice:%s\n", passwordHash(t, "s3cret"))
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+  - path: "drafts/**"
+    password:
+      htpasswd: .htpasswd
+`)
+
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, "public", string(data))
+	// htpasswd 文件不可直接访问
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/.htpasswd")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = submitPassword(t, server, "https://org1.example.com/repo1/drafts/", url.Values{"username": {"bob"}, "password": {"s3cret"}})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = submitPassword(t, server, "https://org1.example.com/repo1/drafts/", url.Values{"username": {"alice"}, "password": {"s3cret"}})
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/drafts/")
+	require.NoError(t, err)
+	assert.Equal(t, "draft", string(data))
+
+	// 未知作用域
+	_, resp, err = server.OpenFile("https://org1.example.com/.pages/password?return_to=%2Frepo1%2F&scope=unknown")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func Test_PasswordHtpasswdNotServedByRoutes(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "public")
+	server.AddFile("org1/repo1/gh-pages/drafts/index.html", "draft")
+	server.AddFile("org1/repo1/gh-pages/.htpasswd", "alice:%s\n", passwordHash(t, "s3cret"))
+	server.AddFile("org1/repo1/gh-pages/_redirects", "/users  /.htpasswd  200\n")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+  - path: "creds"
+    rewrite:
+      target: .htpasswd
+  - path: "**"
+    direct: {}
+  - path: "drafts/**"
+    password:
+      htpasswd: .htpasswd
+`)
+
+	// 用户路由与 _redirects 改写均无法读取 htpasswd 文件
+	for _, target := range []string{
+		"https://org1.example.com/repo1/.htpasswd",
+		"https://org1.example.com/repo1/creds",
+		"https://org1.example.com/repo1/users",
+	} {
+		data, resp, _ := server.OpenFile(target)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, target)
+		assert.NotContains(t, string(data), "alice", target)
+	}
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, "public", string(data))
+}
+
+func Test_PasswordHtpasswdUserRemoved(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "draft")
+	server.AddFile("org1/repo1/gh-pages/.htpasswd", "alice:%s\nbob:%s\n", passwordHash(t, "s3cret"), passwordHash(t, "hunter2"))
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "password:\n  htpasswd: .htpasswd\n")
+
+	resp := submitPassword(t, server, "https://org1.example.com/repo1/", url.Values{"username": {"alice"}, "password": {"s3cret"}})
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, "draft", string(data))
+
+	// 从 htpasswd 中移除用户后已签发的 Cookie 失效
+	server.AddFile("org1/repo1/gh-pages/.htpasswd", "bob:%s\n", passwordHash(t, "hunter2"))
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), "/.pages/password?"), resp.Header.Get("Location"))
+}
+
+func Test_PasswordMissingHtpasswd(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "draft")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "password:\n  htpasswd: .htpasswd\n")
+
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func Test_PasswordRouteTrailingSlashNever(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "public")
+	server.AddFile("org1/repo1/gh-pages/drafts/index.html", "draft")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+trailing_slash: never
+routes:
+  - path: "drafts/**"
+    password:
+      hash: '%s'
+`, passwordHash(t, "s3cret"))
+
+	// 不带结尾斜杠的目录地址同样要求口令
+	resp := submitPassword(t, server, "https://org1.example.com/repo1/drafts", url.Values{"password": {"s3cret"}})
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/drafts")
+	require.NoError(t, err)
+	assert.Equal(t, "draft", string(data))
+}
+
+func Test_PasswordFailureLimit(t *testing.T) {
+	// 两个实例共用同一 KV，失败次数合并计算
+	db, err := kv.NewMemory("")
+	require.NoError(t, err)
+	hash := passwordHash(t, "s3cret")
+	servers := make([]*testcore.TestServer, 2)
+	for i := range servers {
+		servers[i] = testcore.NewTestServerWithKVOptions("example.com", db, db)
+		defer servers[i].Close()
+		servers[i].AddFile("org1/repo1/gh-pages/index.html", "draft")
+		servers[i].AddFile("org1/repo1/gh-pages/.pages.yaml", "password:\n  hash: '%s'\n", hash)
+	}
+
+	_, resp, err := servers[0].OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	location := "https://org1.example.com" + resp.Header.Get("Location")
+	post := func(server *testcore.TestServer, password, remote string) int {
+		req := httptest.NewRequest(http.MethodPost, location, strings.NewReader(url.Values{"password": {password}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = remote
+		_, resp, _ := server.Do(req)
+		return resp.StatusCode
+	}
+	for i := range 10 {
+		assert.Equal(t, http.StatusUnauthorized, post(servers[i%2], "wrong", "192.0.2.1:1234"))
+	}
+	// 超出失败次数后正确口令同样被拒绝，其他客户端不受影响
+	assert.Equal(t, http.StatusTooManyRequests, post(servers[0], "s3cret", "192.0.2.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, post(servers[1], "s3cret", "192.0.2.1:1234"))
+	assert.Equal(t, http.StatusSeeOther, post(servers[1], "s3cret", "192.0.2.2:1234"))
+}
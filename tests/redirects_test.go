@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func Test_RedirectsFile(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/old/kept.html", "kept")
+	server.AddFile("org1/repo1/gh-pages/app/index.html", "app shell")
+	server.AddFile("org1/repo1/gh-pages/closed.html", "store closed")
+	server.AddFile("org1/repo1/gh-pages/_redirects", `
+# 迁移自 Netlify
+/old/*              /new/:splat
+/forced/*           /new/:splat 302!
+/posts/:year/:slug  /blog/:year/:slug 301
+/store id=:id       /products/:id 301
+/app/*              /app/index.html 200
+/shop/*             /closed.html 404
+`)
+
+	// 文件存在时未强制的规则不生效
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/old/kept.html")
+	require.NoError(t, err)
+	assert.Equal(t, "kept", string(data))
+
+	req := httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/old/a/b.html?ref=1", nil)
+	_, resp, _ := server.Do(req)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "/repo1/new/a/b.html?ref=1", resp.Header.Get("Location"))
+
+	server.AddFile("org1/repo1/gh-pages/forced/page.html", "shadowed")
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/forced/page.html", nil)
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/repo1/new/page.html", resp.Header.Get("Location"))
+
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/posts/2024/hello/", nil)
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "/repo1/blog/2024/hello", resp.Header.Get("Location"))
+
+	// 含查询参数条件时不追加原查询参数
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/store?id=42", nil)
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "/repo1/products/42", resp.Header.Get("Location"))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/store")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	data, resp, err = server.OpenFile("https://org1.example.com/repo1/app/users/42")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "app shell", string(data))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/shop/cart")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "store closed", string(body))
+}
+
+func Test_RedirectsFileInvalid(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/_redirects", "/a /b 418\n")
+
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func Test_RedirectsFileIndexSuffix(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/myindex.html", "my index")
+	server.AddFile("org1/repo1/gh-pages/_redirects", `
+/my      /elsewhere 301!
+/docs/*  /guide/:splat 301!
+`)
+
+	// 仅去除完整的 index.html 文件名
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/myindex.html")
+	require.NoError(t, err)
+	assert.Equal(t, "my index", string(data))
+
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/docs/intro/index.html")
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "/repo1/guide/intro", resp.Header.Get("Location"))
+}
+
+func Test_RedirectsFileSchemeRelativeTarget(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/org1.example.com/gh-pages/index.html", "home")
+	server.AddFile("org1/org1.example.com/gh-pages/_redirects", `
+/go/*  /:splat 301
+`)
+
+	// 捕获的路径以斜杠开头时不会生成 //host 形式的跳转
+	for _, target := range []string{
+		"https://org1.example.com/go//evil.example/x",
+		"https://org1.example.com/go/%2Fevil.example/x",
+		"https://org1.example.com/go/%5Cevil.example/x",
+	} {
+		_, resp, _ := server.OpenFile(target)
+		assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode, target)
+		location := resp.Header.Get("Location")
+		assert.True(t, strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//"), location)
+		assert.NotContains(t, location, "\\")
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func Test_AuthRouteProtectsPath(t *testing.T) {
+	provider := &teamAuthProvider{
+		fakeAuthProvider: fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true},
+		teams:            map[string]bool{"org1/admins": true},
+	}
+	server := newAuthTestServer(t, provider)
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/admin/index.html", "admin")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "admin/**"
+  auth:
+    teams: [org1/admins]
+`)
+
+	// 路由之外的页面保持公开
+	data, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	require.NoError(t, err)
+	assert.Equal(t, "home", string(data))
+	assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"))
+
+	_, resp, err = server.OpenRequest(http.MethodGet, "https://org1.example.com/repo1/admin/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/.pages/auth/login?return_to=%2Frepo1%2Fadmin%2F", resp.Header.Get("Location"))
+
+	loginThroughAuth(t, server, "/repo1/admin/")
+	data, resp, err = server.OpenFile("https://org1.example.com/repo1/admin/")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", string(data))
+	assert.Equal(t, "private, max-age=60", resp.Header.Get("Cache-Control"))
+
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "admin/**"
+  auth:
+    teams: [org1/other]
+`)
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/admin/")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func Test_AuthRouteTrailingSlashNever(t *testing.T) {
+	provider := &teamAuthProvider{
+		fakeAuthProvider: fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true},
+		teams:            map[string]bool{"org1/admins": true},
+	}
+	server := newAuthTestServer(t, provider)
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/admin/index.html", "admin")
+	server.AddFile("org1/repo1/gh-pages/secret/index.html", "secret")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+trailing_slash: never
+routes:
+- path: "admin/**"
+  auth:
+    teams: [org1/admins]
+- path: "secret/**"
+  block: {}
+`)
+
+	// 不带结尾斜杠的目录地址同样受路由保护
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/admin")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/.pages/auth/login?return_to=%2Frepo1%2Fadmin", resp.Header.Get("Location"))
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/secret")
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+
+	loginThroughAuth(t, server, "/repo1/admin")
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/admin")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", string(data))
+}
+
+func Test_AuthRouteWithoutRulesUsesRepoPermission(t *testing.T) {
+	server := newAuthTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: false})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/admin/index.html", "admin")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "admin/**"
+  auth: {}
+`)
+
+	loginThroughAuth(t, server, "/repo1/admin/")
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/admin/")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func Test_AuthRouteRequiresAuthProvider(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/admin/index.html", "admin")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "admin/**"
+  auth:
+    users: [dragon]
+`)
+
+	_, resp, _ := server.OpenFile("https://org1.example.com/repo1/admin/")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
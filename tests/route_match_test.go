@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func Test_RouteMatchMethods(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/api/data.json", `{"static":true}`)
+	server.AddFile("org1/repo1/gh-pages/index.js", `
+serve(function(request) {
+  return new Response("dynamic " + request.method)
+})
+`)
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "api/**"
+  match:
+    methods: [POST]
+  js:
+    exec: index.js
+`)
+
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/api/data.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"static":true}`, string(data))
+
+	data, _, err = server.OpenRequest(http.MethodPost, "https://org1.example.com/repo1/api/data.json", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "dynamic POST", string(data))
+}
+
+func Test_RouteMatchHostHeaderQuery(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "stable")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+alias:
+  - "*.preview.example.org"
+routes:
+- path: "**"
+  match:
+    headers:
+      X-Channel: "beta*"
+    query:
+      lang: ""
+  redirect:
+    targets: [header.example.net]
+- path: "**"
+  match:
+    query:
+      channel: beta
+  redirect:
+    targets: [query.example.net]
+- path: "**"
+  match:
+    hosts: ["beta.preview.example.org"]
+  block:
+    code: 451
+`)
+
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/")
+	assert.NoError(t, err)
+	assert.Equal(t, "stable", string(data))
+
+	req := httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/?lang=en", nil)
+	req.Header.Set("X-Channel", "beta-2")
+	_, resp, _ := server.Do(req)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://header.example.net/?lang=en", resp.Header.Get("Location"))
+
+	// 所有条件都需要满足
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/", nil)
+	req.Header.Set("X-Channel", "beta-2")
+	data, _, err = server.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "stable", string(data))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/?channel=beta")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://query.example.net/?channel=beta", resp.Header.Get("Location"))
+
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/?channel=stable")
+	assert.NoError(t, err)
+	assert.Equal(t, "stable", string(data))
+
+	_, resp, _ = server.OpenFile("https://beta.preview.example.org/")
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, resp.StatusCode)
+
+	data, _, err = server.OpenFile("https://alpha.preview.example.org/")
+	assert.NoError(t, err)
+	assert.Equal(t, "stable", string(data))
+}
+
+func Test_RouteMatchInvalidConfig(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "api/**"
+  match:
+    methods: ["NOT A METHOD"]
+  block:
+    code: 403
+`)
+
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+)
+
+func Test_RouteParamsRedirect(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "posts/:year/:slug"
+  redirect:
+    to: /blog/:year/:slug
+    code: 301
+- path: "legacy/**"
+  redirect:
+    to: archive.example.net/old/:splat
+`)
+
+	req := httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/posts/2024/hello?ref=feed", nil)
+	_, resp, _ := server.Do(req)
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://org1.example.com/blog/2024/hello?ref=feed", resp.Header.Get("Location"))
+
+	req = httptest.NewRequest(http.MethodGet, "https://org1.example.com/repo1/legacy/a/b.html", nil)
+	_, resp, _ = server.Do(req)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://archive.example.net/old/a/b.html", resp.Header.Get("Location"))
+
+	// 参数个数不匹配时不生效
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/posts/2024")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func Test_RouteParamsDirectAndJS(t *testing.T) {
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/v2/docs/v2/guide.html", "v2 guide")
+	server.AddFile("org1/repo1/gh-pages/params.js", `
+serve(function(request) {
+  return new Response(request.params.id + ":" + request.params.splat + ":" + Object.isFrozen(request.params))
+})
+`)
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "docs/:version/*"
+  direct:
+    prefix: ":version"
+- path: "users/:id/**"
+  js:
+    exec: params.js
+`)
+
+	data, _, err := server.OpenFile("https://org1.example.com/repo1/docs/v2/guide.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2 guide", string(data))
+
+	data, _, err = server.OpenFile("https://org1.example.com/repo1/users/42/profile")
+	assert.NoError(t, err)
+	assert.Equal(t, "42:profile:true", string(data))
+}
+
+func Test_RouteParamsReverseProxyTarget(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	}))
+	defer upstream.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(upstream.Certificate())
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	originalTransport := http.DefaultTransport
+	http.DefaultTransport = transport
+	defer func() {
+		http.DefaultTransport = originalTransport
+	}()
+
+	server := testcore.NewDefaultTestServer()
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", `
+routes:
+- path: "users/:id/:tab"
+  reverse_proxy:
+    target: %q
+`, upstream.URL+"/v1/users/:id/:tab")
+
+	data, resp, err := server.OpenFile("https://org1.example.com/repo1/users/42/repos?q=1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/v1/users/42/repos?q=1", string(data))
+}
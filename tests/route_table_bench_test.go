@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.d7z.net/gitea-pages/pkg"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+	testcore "gopkg.d7z.net/gitea-pages/tests/core"
+	"gopkg.d7z.net/middleware/kv"
+)
+
+const benchRouteCount = 500
+
+func benchRouteFilters() []core.Filter {
+	filters := make([]core.Filter, 0, benchRouteCount+2)
+	filters = append(filters, core.Filter{Path: "**", Type: "404"})
+	for i := 0; i < benchRouteCount; i++ {
+		filters = append(filters, core.Filter{
+			Path:   fmt.Sprintf("section%d/:page/**", i),
+			Type:   "block",
+			Params: core.Params{"code": 403},
+		})
+	}
+	return append(filters, core.Filter{Path: "**", Type: "direct"})
+}
+
+func BenchmarkRouteTableMatch(b *testing.B) {
+	noop := func(core.Params) (core.FilterCall, error) {
+		return func(core.FilterContext, http.ResponseWriter, *http.Request, core.NextCall) error { return nil }, nil
+	}
+	table, err := core.NewRouteTable(benchRouteFilters(), map[string]core.FilterInstance{
+		"404": noop, "block": noop, "direct": noop,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if hits := table.Match("section250/intro/index.html", nil); len(hits) != 3 {
+			b.Fatalf("unexpected hits: %d", len(hits))
+		}
+	}
+}
+
+func BenchmarkServePageManyRoutes(b *testing.B) {
+	b.Setenv("BM", "1")
+	db, err := kv.NewMemory("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	server := testcore.NewTestServerWithKVOptions("example.com", db, db,
+		pkg.WithMetaCache(db.Child("cache"), time.Hour, time.Hour, 0))
+	defer server.Close()
+
+	var config strings.Builder
+	config.WriteString("routes:\n")
+	for i := 0; i < benchRouteCount; i++ {
+		fmt.Fprintf(&config, "- path: \"section%d/:page/**\"\n  block:\n    code: 403\n", i)
+	}
+	server.AddFile("org1/repo1/gh-pages/index.html", "home")
+	server.AddFile("org1/repo1/gh-pages/docs/index.html", "docs")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", "%s", config.String())
+
+	if _, _, err = server.OpenFile("https://org1.example.com/repo1/docs/"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err = server.OpenFile("https://org1.example.com/repo1/docs/"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
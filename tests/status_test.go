@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.d7z.net/gitea-pages/pkg/core"
+)
+
+type writeAuthProvider struct {
+	fakeAuthProvider
+	writable bool
+}
+
+func (w *writeAuthProvider) AuthorizeRepoWrite(context.Context, *core.AuthSession, string, string) (bool, error) {
+	return w.writable, nil
+}
+
+const brokenPagesConfig = `
+routes:
+- path: "old/**"
+  redirect:
+    targets: [new.example.org]
+    code: 200
+`
+
+func Test_ConfigErrorHiddenFromVisitors(t *testing.T) {
+	server := newAuthTestServer(t, &writeAuthProvider{
+		fakeAuthProvider: fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true},
+		writable:         false,
+	})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello world")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", brokenPagesConfig)
+
+	for range 2 {
+		_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "page configuration error\n", string(body))
+		assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	}
+
+	// 未登录时无法确认可见性，状态接口与不存在的仓库表现一致
+	_, resp, err := server.OpenFile("https://org1.example.com/.pages/status/org1/repo1")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"owner":"org1","repo":"repo1","status":"not_found"}`, string(body))
+
+	// 登录但没有写权限同样只看到通用错误
+	loginThroughAuth(t, server, "/repo1/")
+	_, resp, _ = server.OpenFile("https://org1.example.com/repo1/")
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, "page configuration error\n", string(body))
+
+	data, resp, err := server.OpenFile("https://org1.example.com/.pages/status/org1/repo1")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"owner":"org1","repo":"repo1","status":"error"}`, string(data))
+}
+
+func Test_ConfigErrorShownToWriters(t *testing.T) {
+	server := newAuthTestServer(t, &writeAuthProvider{
+		fakeAuthProvider: fakeAuthProvider{session: authSession("u1", "dragon"), authorized: true},
+		writable:         true,
+	})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello world")
+	server.AddFile("org1/repo1/gh-pages/.pages.yaml", brokenPagesConfig)
+	loginThroughAuth(t, server, "/repo1/")
+
+	_, resp, err := server.OpenFile("https://org1.example.com/repo1/")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "org1/repo1@gh-pages")
+	assert.Contains(t, string(body), `line 3: invalid redirect params in route "old/**": invalid code: 200`)
+
+	data, _, err := server.OpenFile("https://org1.example.com/.pages/status/org1/repo1")
+	require.NoError(t, err)
+	var status core.PageStatus
+	require.NoError(t, json.Unmarshal(data, &status))
+	assert.Equal(t, core.PageStatusError, status.Status)
+	assert.Equal(t, "gh-pages", status.CommitID)
+	assert.Equal(t, `line 3: invalid redirect params in route "old/**": invalid code: 200`, status.Error)
+}
+
+func Test_StatusEndpoint(t *testing.T) {
+	server := newAuthTestServer(t, &fakeAuthProvider{session: authSession("u1", "dragon"), authorized: false})
+	defer server.Close()
+	server.AddFile("org1/repo1/gh-pages/index.html", "hello world")
+	server.AddFile("org1/private/gh-pages/index.html", "private")
+	server.AddFile("org1/private/gh-pages/.pages.yaml", "private: true\n")
+
+	data, _, err := server.OpenFile("https://pages.example.com/.pages/status/org1/repo1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"owner":"org1","repo":"repo1","status":"ok","commit_id":"gh-pages"}`, string(data))
+
+	// 无权访问的私有仓库与不存在的仓库表现一致
+	loginThroughAuth(t, server, "/")
+	_, resp, err := server.OpenFile("https://org1.example.com/.pages/status/org1/private")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"owner":"org1","repo":"private","status":"not_found"}`, string(body))
+
+	_, resp, _ = server.OpenFile("https://org1.example.com/.pages/status/org1")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}